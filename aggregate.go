@@ -0,0 +1,78 @@
+package httpstats
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/tkuchiki/gohttpstats/parsers"
+	"github.com/tkuchiki/parsetime"
+)
+
+// AggregateResult holds the line counters of one Aggregate run.
+type AggregateResult struct {
+	// Read is the number of lines returned by the parser, including skipped and failed ones.
+	Read int
+	// Skipped is the number of lines rejected by the filter.
+	Skipped int
+	// Failed is the number of lines the parser could not parse.
+	Failed int
+	// InvalidTime is the number of lines counted without their time,
+	// which could not be parsed.
+	InvalidTime int
+}
+
+// Aggregate reads parser until io.EOF and sets every line that passes the filter.
+// Lines the parser reports with SkipReadLineErr are counted as failed and skipped;
+// any other parser error, or the cancellation of ctx, stops the run and is returned.
+func (hs *HTTPStats) Aggregate(ctx context.Context, parser parsers.Parser) (*AggregateResult, error) {
+	result, err := aggregate(ctx, parser, hs.parseTime, hs.doFilterTime, hs.setHTTPStat)
+	hs.sortSamples()
+
+	return result, err
+}
+
+// aggregate runs the Aggregate loop. The time of each line is parsed once
+// with p and passed to filter and set, along with the error of parsing it.
+// Lines for which set returns InvalidTimeErr are counted as such, and any
+// other error as failed.
+func aggregate(ctx context.Context, parser parsers.Parser, p parsetime.ParseTime, filter func(uri, status string, t time.Time, timeErr error) bool, set func(stat *parsers.HTTPStat, t time.Time, timeErr error) error) (*AggregateResult, error) {
+	result := &AggregateResult{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		stat, err := parser.Parse()
+		if err == io.EOF {
+			return result, nil
+		}
+
+		result.Read++
+
+		if err == SkipReadLineErr {
+			result.Failed++
+			continue
+		} else if err != nil {
+			result.Failed++
+			return result, err
+		}
+
+		t, timeErr := parseStatTime(p, stat.Time)
+		if !filter(stat.Uri, strconv.Itoa(stat.Status), t, timeErr) {
+			result.Skipped++
+			continue
+		}
+
+		err = set(stat, t, timeErr)
+		if err == InvalidTimeErr {
+			result.InvalidTime++
+		} else if err != nil {
+			result.Failed++
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package httpstats
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tkuchiki/gohttpstats/options"
+	"github.com/tkuchiki/gohttpstats/parsers"
+)
+
+func TestAggregate(t *testing.T) {
+	data := bytes.NewBufferString(`{"method":"GET","uri":"/foo","status":200,"size":10,"apptime":0.1}
+{"method":"GET","uri":"/foo","status":500,"size":20,"apptime":0.3}
+{"method":"GET","uri":"/healthcheck","status":200,"size":2,"apptime":0.001}
+{"method":"GET","uri":"/foo","status":"-","size":0,"apptime":0.1}
+{"method":"GET","uri":"/foo","status":200,"size":0,"apptime":"x"}
+{"time":"broken","method":"GET","uri":"/foo","status":200,"size":0,"apptime":0.1}
+`)
+
+	keys := parsers.NewJSONKeys("uri", "apptime", "reqtime", "size", "status", "method", "time")
+	parser := parsers.NewJSONParser(data, keys, false)
+
+	stats := NewHTTPStats(true, false, false, NewPrintOptions())
+	err := stats.InitFilter(stats_options.NewOptions(stats_options.Excludes([]string{"^/healthcheck$"})))
+	assert.Nil(t, err)
+
+	result, err := stats.Aggregate(context.Background(), parser)
+	assert.Nil(t, err)
+	assert.Equal(t, &AggregateResult{Read: 6, Skipped: 1, Failed: 1, InvalidTime: 1}, result)
+
+	s := stats.Stats()
+	assert.Equal(t, 1, stats.CountUris())
+	assert.Equal(t, 4, s[0].Cnt)
+	assert.Equal(t, 1, s[0].StatusInvalid)
+	assert.Equal(t, 1, s[0].Status5xx)
+}
+
+func TestAggregateTimeRange(t *testing.T) {
+	data := bytes.NewBufferString(`{"time":"2020-01-01T00:00:00Z","method":"GET","uri":"/foo","status":200,"size":0,"apptime":0.1}
+{"time":"2020-01-01T00:00:10Z","method":"GET","uri":"/foo","status":200,"size":0,"apptime":0.1}
+{"time":"2020-01-01T00:00:20Z","method":"GET","uri":"/foo","status":200,"size":0,"apptime":0.1}
+{"time":"2020-01-01T00:01:00Z","method":"GET","uri":"/foo","status":200,"size":0,"apptime":0.1}
+{"time":"broken","method":"GET","uri":"/foo","status":200,"size":0,"apptime":0.1}
+{"method":"GET","uri":"/foo","status":200,"size":0,"apptime":0.1}
+`)
+
+	keys := parsers.NewJSONKeys("uri", "apptime", "reqtime", "size", "status", "method", "time")
+	parser := parsers.NewJSONParser(data, keys, false)
+
+	stats := NewHTTPStats(true, false, false, NewPrintOptions())
+	err := stats.InitFilter(stats_options.NewOptions(stats_options.Location("UTC"), stats_options.EndTime("2020-01-01T00:00:30Z")))
+	assert.Nil(t, err)
+
+	result, err := stats.Aggregate(context.Background(), parser)
+	assert.Nil(t, err)
+	assert.Equal(t, &AggregateResult{Read: 6, Skipped: 3}, result)
+	assert.Equal(t, 3, stats.Stats()[0].Cnt)
+	assert.Equal(t, 0.15, stats.Stats()[0].Throughput())
+}
+
+func TestAggregateCanceled(t *testing.T) {
+	data := bytes.NewBufferString(`{"method":"GET","uri":"/foo","status":200,"size":10,"apptime":0.1}`)
+
+	keys := parsers.NewJSONKeys("uri", "apptime", "reqtime", "size", "status", "method", "time")
+	parser := parsers.NewJSONParser(data, keys, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stats := NewHTTPStats(false, false, false, NewPrintOptions())
+	result, err := stats.Aggregate(ctx, parser)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, result.Read)
+}
@@ -0,0 +1,104 @@
+package httpstats
+
+import (
+	"fmt"
+)
+
+// apdex counts the requests of an entry by the Apdex zones of Threshold T:
+// satisfied (<= T), tolerating (<= 4T) and frustrated. Server errors are
+// frustrated whatever their response time.
+type apdex struct {
+	Threshold  float64 `yaml:"threshold"`
+	Satisfied  int     `yaml:"satisfied"`
+	Tolerating int     `yaml:"tolerating"`
+	Frustrated int     `yaml:"frustrated"`
+}
+
+func newApdex(threshold float64) *apdex {
+	return &apdex{
+		Threshold: threshold,
+	}
+}
+
+func (a *apdex) add(restime float64, status int) {
+	switch {
+	case status >= 500 && status <= 599:
+		a.Frustrated++
+	case restime <= a.Threshold:
+		a.Satisfied++
+	case restime <= 4*a.Threshold:
+		a.Tolerating++
+	default:
+		a.Frustrated++
+	}
+}
+
+// Score returns the Apdex score between 0 and 1.
+func (a *apdex) Score() float64 {
+	total := a.Satisfied + a.Tolerating + a.Frustrated
+	if total == 0 {
+		return 0
+	}
+
+	return (float64(a.Satisfied) + float64(a.Tolerating)/2) / float64(total)
+}
+
+// ApdexScore returns the Apdex score, or 0 when Apdex is not enabled.
+func (hs *httpStat) ApdexScore() float64 {
+	if hs.Apdex == nil {
+		return 0
+	}
+
+	return hs.Apdex.Score()
+}
+
+// SetApdexThreshold enables Apdex for new entries with the satisfied
+// threshold t in seconds. 0 disables it.
+func (hs *HTTPStats) SetApdexThreshold(t float64) error {
+	if t < 0 {
+		return fmt.Errorf("apdex threshold must not be negative: %v", t)
+	}
+
+	hs.apdexThreshold = t
+
+	return nil
+}
+
+// SetApdexThresholds overrides the Apdex threshold of the uri groups by
+// their template, as printed. It must follow the setting of the uri groups.
+func (hs *HTTPStats) SetApdexThresholds(thresholds map[string]float64) error {
+	for template, t := range thresholds {
+		if t < 0 {
+			return fmt.Errorf("apdex threshold must not be negative: %s: %v", template, t)
+		}
+
+		found := false
+		for _, g := range hs.uriCapturingGroups {
+			if g.Template() == template {
+				g.apdexThreshold = t
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("apdex threshold for unknown uri group: %s", template)
+		}
+	}
+
+	return nil
+}
+
+// apdexThresholdOf returns the Apdex threshold of an entry of group,
+// which is nil for the entries of no group.
+func (hs *HTTPStats) apdexThresholdOf(group *URIGroup) float64 {
+	if group != nil && group.apdexThreshold > 0 {
+		return group.apdexThreshold
+	}
+
+	return hs.apdexThreshold
+}
+
+// ApdexThreshold returns the Apdex threshold of the group, or 0 for the global one.
+func (g *URIGroup) ApdexThreshold() float64 {
+	return g.apdexThreshold
+}
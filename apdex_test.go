@@ -0,0 +1,35 @@
+package httpstats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApdex(t *testing.T) {
+	hs := NewHTTPStats(false, false, false, NewPrintOptions())
+	assert.Nil(t, hs.SetURICapturingGroups([]string{"/slow/:id"}))
+	assert.Nil(t, hs.SetApdexThreshold(0.5))
+	assert.Nil(t, hs.SetApdexThresholds(map[string]float64{"/slow/:id": 2}))
+	assert.NotNil(t, hs.SetApdexThresholds(map[string]float64{"/unknown/:id": 2}))
+
+	// satisfied, tolerating, frustrated and a fast server error
+	for _, r := range []struct {
+		restime float64
+		status  int
+	}{{0.5, 200}, {1.5, 200}, {2.5, 200}, {0.1, 503}} {
+		hs.Set("/fast", "GET", r.status, r.restime, 0, 0)
+		hs.Set("/slow/1", "GET", r.status, r.restime, 0, 0)
+	}
+
+	fast := hs.Stats()[0]
+	assert.Equal(t, &apdex{Threshold: 0.5, Satisfied: 1, Tolerating: 1, Frustrated: 2}, fast.Apdex)
+	assert.Equal(t, 0.375, fast.ApdexScore())
+
+	slow := hs.Stats()[1]
+	assert.Equal(t, &apdex{Threshold: 2, Satisfied: 2, Tolerating: 1, Frustrated: 1}, slow.Apdex)
+	assert.Equal(t, 0.625, slow.ApdexScore())
+
+	hs.Sort("apdex", true)
+	assert.Equal(t, "/slow/:id", hs.Stats()[0].Uri)
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tkuchiki/gohttpstats"
+	"github.com/tkuchiki/gohttpstats/options"
+	"github.com/tkuchiki/gohttpstats/parsers"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// logOptions are the flags that select and aggregate the log,
+// shared by the commands that read one.
+type logOptions struct {
+	config            *string
+	file              *string
+	parser            *string
+	pattern           *string
+	queryString       *bool
+	apptimeLabel      *string
+	reqtimeLabel      *string
+	statusLabel       *string
+	sizeLabel         *string
+	reqSizeLabel      *string
+	methodLabel       *string
+	uriLabel          *string
+	timeLabel         *string
+	uaLabel           *string
+	limit             *int
+	overflowUri       *string
+	includes          *[]string
+	excludes          *[]string
+	includeStatuses   *[]string
+	excludeStatuses   *[]string
+	aggregates        *string
+	uriGroups         *[]string
+	uriNormalizers    *[]string
+	openAPI           *string
+	startTime         *string
+	endTime           *string
+	startTimeDuration *string
+	endTimeDuration   *string
+	location          *string
+	percentileBackend *string
+	sketchAccuracy    *float64
+	apdexThreshold    *float64
+}
+
+func registerLogFlags(cmd *kingpin.CmdClause) *logOptions {
+	return &logOptions{
+		config:            cmd.Flag("config", "YAML config file; command-line flags take precedence over it").Short('c').String(),
+		file:              cmd.Flag("file", "access log file; reads stdin when empty or \"-\"").Short('f').String(),
+		parser:            cmd.Flag("parser", "log format: ltsv, json or regexp").Enum("", "ltsv", "json", "regexp"),
+		pattern:           cmd.Flag("pattern", "regexp parser pattern or preset name (common, combined, nginx_main)").String(),
+		queryString:       cmd.Flag("query-string", "include query string keys in the uri").Short('q').Bool(),
+		apptimeLabel:      cmd.Flag("apptime-label", "apptime label").String(),
+		reqtimeLabel:      cmd.Flag("reqtime-label", "reqtime label").String(),
+		statusLabel:       cmd.Flag("status-label", "status label").String(),
+		sizeLabel:         cmd.Flag("size-label", "size label").String(),
+		reqSizeLabel:      cmd.Flag("reqsize-label", "request body size label").String(),
+		methodLabel:       cmd.Flag("method-label", "method label").String(),
+		uriLabel:          cmd.Flag("uri-label", "uri label").String(),
+		timeLabel:         cmd.Flag("time-label", "time label").String(),
+		uaLabel:           cmd.Flag("ua-label", "user agent label, for the ua_class aggregate (default ua, or user_agent for the regexp presets)").String(),
+		limit:             cmd.Flag("limit", "maximum number of distinct method and uri pairs").Int(),
+		overflowUri:       cmd.Flag("overflow-uri", "uri of the entry counting requests beyond --limit").String(),
+		includes:          cmd.Flag("includes", "include uris matching the regexp (repeatable)").Strings(),
+		excludes:          cmd.Flag("excludes", "exclude uris matching the regexp (repeatable)").Strings(),
+		includeStatuses:   cmd.Flag("include-statuses", "include statuses matching the regexp (repeatable)").Strings(),
+		excludeStatuses:   cmd.Flag("exclude-statuses", "exclude statuses matching the regexp (repeatable)").Strings(),
+		aggregates:        cmd.Flag("aggregates", "group by these dimensions: uri, method, status, ua_class or any log field such as host (comma separated, default method,uri)").String(),
+		uriGroups:         cmd.Flag("uri-groups", "uri group, a route pattern like /users/:id or a regexp (repeatable, first match wins)").Short('m').Strings(),
+		uriNormalizers:    cmd.Flag("uri-normalizers", "replace uri segments with placeholders: id, uuid, hex, base64, date, email or all (repeatable)").Strings(),
+		openAPI:           cmd.Flag("openapi", "OpenAPI 3 document whose operations group the uris; other uris are counted as \"unmatched\"").String(),
+		startTime:         cmd.Flag("start-time", "only lines at or after this time").String(),
+		endTime:           cmd.Flag("end-time", "only lines at or before this time").String(),
+		startTimeDuration: cmd.Flag("start-time-duration", "only lines newer than now minus this duration").String(),
+		endTimeDuration:   cmd.Flag("end-time-duration", "only lines older than now minus this duration").String(),
+		location:          cmd.Flag("location", "time zone of the times in the log").String(),
+		percentileBackend: cmd.Flag("percentile-backend", "exact keeps every sample, sketch bounds memory").Enum("", "exact", "sketch"),
+		sketchAccuracy:    cmd.Flag("sketch-accuracy", "relative accuracy of the sketch percentiles (e.g. 0.01)").Float64(),
+		apdexThreshold:    cmd.Flag("apdex-threshold", "satisfied response time threshold T of the apdex column, in seconds").Float64(),
+	}
+}
+
+// loadOptions merges the options in increasing order of precedence:
+// built-in defaults, the YAML config file, then command-line flags,
+// those of l followed by opt.
+// A flag only overrides the config when it is set to a non-zero value,
+// so boolean settings enabled in the config cannot be disabled by a flag.
+func (l *logOptions) loadOptions(opt ...stats_options.Option) (*stats_options.Options, error) {
+	var err error
+	opts := stats_options.NewOptions()
+
+	if *l.config != "" {
+		var f *os.File
+		f, err = os.Open(*l.config)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		opts, err = stats_options.LoadOptionsFromReader(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts = stats_options.SetOptions(opts,
+		stats_options.File(*l.file),
+		stats_options.Parser(*l.parser),
+		stats_options.Pattern(*l.pattern),
+		stats_options.QueryString(*l.queryString),
+		stats_options.ApptimeLabel(*l.apptimeLabel),
+		stats_options.ReqtimeLabel(*l.reqtimeLabel),
+		stats_options.StatusLabel(*l.statusLabel),
+		stats_options.SizeLabel(*l.sizeLabel),
+		stats_options.ReqSizeLabel(*l.reqSizeLabel),
+		stats_options.MethodLabel(*l.methodLabel),
+		stats_options.UriLabel(*l.uriLabel),
+		stats_options.TimeLabel(*l.timeLabel),
+		stats_options.UaLabel(*l.uaLabel),
+		stats_options.Limit(*l.limit),
+		stats_options.OverflowUri(*l.overflowUri),
+		stats_options.Includes(*l.includes),
+		stats_options.Excludes(*l.excludes),
+		stats_options.IncludeStatuses(*l.includeStatuses),
+		stats_options.ExcludeStatuses(*l.excludeStatuses),
+		stats_options.CSVAggregates(*l.aggregates),
+		stats_options.UriGroups(*l.uriGroups),
+		stats_options.UriNormalizers(*l.uriNormalizers),
+		stats_options.OpenAPI(*l.openAPI),
+		stats_options.StartTime(*l.startTime),
+		stats_options.EndTime(*l.endTime),
+		stats_options.StartTimeDuration(*l.startTimeDuration),
+		stats_options.EndTimeDuration(*l.endTimeDuration),
+		stats_options.Location(*l.location),
+		stats_options.PercentileBackend(*l.percentileBackend),
+		stats_options.SketchAccuracy(*l.sketchAccuracy),
+		stats_options.ApdexThreshold(*l.apdexThreshold),
+	)
+
+	return stats_options.SetOptions(opts, opt...), nil
+}
+
+// outputOptions are the flags that print the stats.
+type outputOptions struct {
+	sort        *string
+	reverse     *bool
+	tsv         *bool
+	format      *string
+	noHeaders   *bool
+	columns     *string
+	percentiles *string
+}
+
+func registerOutputFlags(cmd *kingpin.CmdClause) *outputOptions {
+	return &outputOptions{
+		sort:        cmd.Flag("sort", "sort key").String(),
+		reverse:     cmd.Flag("reverse", "reverse the sort order").Short('r').Bool(),
+		tsv:         cmd.Flag("tsv", "print as TSV (same as --format tsv)").Bool(),
+		format:      cmd.Flag("format", "output format: table, tsv, csv, markdown, json or ndjson").Enum("", "table", "tsv", "csv", "markdown", "json", "ndjson"),
+		noHeaders:   cmd.Flag("noheaders", "print without headers (TSV, CSV and Markdown)").Bool(),
+		columns:     cmd.Flag("columns", "printed columns (comma separated keys, e.g. count,method,uri,p99,avg)").Short('o').String(),
+		percentiles: cmd.Flag("percentiles", "percentile columns (comma separated, e.g. 50,95,99.9)").String(),
+	}
+}
+
+func (o *outputOptions) options() ([]stats_options.Option, error) {
+	percentiles, err := splitFloatCSV(*o.percentiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return []stats_options.Option{
+		stats_options.Sort(*o.sort),
+		stats_options.Reverse(*o.reverse),
+		stats_options.Tsv(*o.tsv),
+		stats_options.Format(*o.format),
+		stats_options.NoHeaders(*o.noHeaders),
+		stats_options.CSVColumns(*o.columns),
+		stats_options.Percentiles(percentiles),
+	}, nil
+}
+
+type analyzeOptions struct {
+	*logOptions
+	*outputOptions
+	interval *string
+	dump     *string
+}
+
+func registerAnalyzeFlags(cmd *kingpin.CmdClause) *analyzeOptions {
+	return &analyzeOptions{
+		logOptions:    registerLogFlags(cmd),
+		outputOptions: registerOutputFlags(cmd),
+		dump:          cmd.Flag("dump", "write the stats to this YAML file, for the merge command, instead of printing them").String(),
+		interval:      cmd.Flag("interval", "print a time series of the stats per window of this duration (e.g. 1m, 5m, 1h)").String(),
+	}
+}
+
+func (a *analyzeOptions) loadOptions() (*stats_options.Options, error) {
+	opt, err := a.outputOptions.options()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.logOptions.loadOptions(append(opt,
+		stats_options.Interval(*a.interval),
+		stats_options.Dump(*a.dump),
+	)...)
+}
+
+func runAnalyze(a *analyzeOptions) error {
+	opts, err := a.loadOptions()
+	if err != nil {
+		return err
+	}
+
+	if opts.Interval != "" {
+		if opts.Dump != "" {
+			return fmt.Errorf("--dump cannot be used with --interval")
+		}
+
+		return runTimeSeries(opts)
+	}
+
+	hs, err := analyzeLog(opts)
+	if err != nil {
+		return err
+	}
+
+	hs.SortWithOptions()
+	if opts.Dump != "" {
+		err = dumpStats(hs, opts.Dump)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = hs.Print()
+		if err != nil {
+			return err
+		}
+	}
+
+	if hs.OverflowCount() > 0 {
+		fmt.Fprintf(os.Stderr, "%d requests exceeded the limit of %d uris and were counted as %s\n",
+			hs.OverflowCount(), opts.Limit, opts.OverflowUri)
+	}
+
+	return nil
+}
+
+// analyzeLog aggregates the log of opts.
+func analyzeLog(opts *stats_options.Options) (*httpstats.HTTPStats, error) {
+	r, err := openLog(opts.File)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	hs, parser, err := newStatsParser(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := hs.Aggregate(context.Background(), parser)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.InvalidTime > 0 {
+		fmt.Fprintf(os.Stderr, "%d requests had a time that could not be parsed and are not in the throughput\n", result.InvalidTime)
+	}
+
+	return hs, nil
+}
+
+func runTimeSeries(opts *stats_options.Options) error {
+	interval, err := time.ParseDuration(opts.Interval)
+	if err != nil {
+		return err
+	}
+
+	r, err := openLog(opts.File)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	hs, parser, err := newStatsParser(r, opts)
+	if err != nil {
+		return err
+	}
+
+	ts, err := httpstats.NewTimeSeries(hs, interval, opts.Location)
+	if err != nil {
+		return err
+	}
+
+	_, err = ts.Aggregate(context.Background(), parser)
+	if err != nil {
+		return err
+	}
+
+	return ts.Print()
+}
+
+func dumpStats(hs *httpstats.HTTPStats, file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	err = hs.DumpStats(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func openLog(file string) (io.ReadCloser, error) {
+	if file == "" || file == "-" {
+		return os.Stdin, nil
+	}
+
+	return os.Open(file)
+}
+
+func newHTTPStats(opts *stats_options.Options) (*httpstats.HTTPStats, error) {
+	po := httpstats.NewPrintOptions()
+	if opts.Format != "" {
+		po.SetFormat(opts.Format)
+	} else if opts.Tsv {
+		po.SetFormat("tsv")
+	}
+	po.SetNoHeaders(opts.NoHeaders)
+
+	hs := httpstats.NewHTTPStats(true, false, false, po)
+	hs.SetOptions(opts)
+	hs.SetLimit(opts.Limit, opts.OverflowUri)
+	hs.SetAggregates(opts.Aggregates)
+	hs.SetUserAgentField(userAgentLabel(opts))
+
+	err := hs.SetColumns(opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	err = hs.SetPercentileBackend(opts.PercentileBackend, opts.SketchAccuracy)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.Percentiles) > 0 {
+		err = hs.SetPercentiles(opts.Percentiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = hs.InitFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = hs.SetURICapturingGroups(opts.UriGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	err = hs.SetURINormalizers(opts.UriNormalizers)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.OpenAPI != "" {
+		groups, err := httpstats.LoadOpenAPIFile(opts.OpenAPI)
+		if err != nil {
+			return nil, err
+		}
+
+		hs.SetURIGroups(append(hs.URIGroups(), groups...))
+		hs.SetUnmatchedURI(httpstats.DefaultUnmatchedUri)
+	}
+
+	err = hs.SetApdexThreshold(opts.ApdexThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	err = hs.SetApdexThresholds(opts.ApdexThresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	return hs, nil
+}
+
+func splitFloatCSV(csv string) ([]float64, error) {
+	values := make([]float64, 0)
+	for _, v := range stats_options.SplitCSV(csv) {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, f)
+	}
+
+	return values, nil
+}
+
+// newStatsParser returns the stats configured by opts and a parser of r
+// that extracts the log fields they are aggregated by.
+func newStatsParser(r io.Reader, opts *stats_options.Options) (*httpstats.HTTPStats, parsers.Parser, error) {
+	hs, err := newHTTPStats(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parser, err := newParser(r, opts, hs.Fields())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return hs, parser, nil
+}
+
+// userAgentLabel returns the label of the user agent: that of opts, the
+// group of the regexp preset or pattern that captures it, or the default.
+func userAgentLabel(opts *stats_options.Options) string {
+	if opts.UaLabel != "" {
+		return opts.UaLabel
+	}
+
+	if opts.Parser == "regexp" {
+		if field := parsers.RegexpUserAgentField(opts.Pattern); field != "" {
+			return field
+		}
+	}
+
+	return stats_options.DefaultUaLabelOption
+}
+
+// newParser returns the parser selected by opts,
+// which also extracts the extra log fields.
+func newParser(r io.Reader, opts *stats_options.Options, fields []string) (parsers.Parser, error) {
+	switch opts.Parser {
+	case "ltsv":
+		label := parsers.NewLTSVLabel(opts.UriLabel, opts.ApptimeLabel, opts.ReqtimeLabel,
+			opts.SizeLabel, opts.StatusLabel, opts.MethodLabel, opts.TimeLabel)
+		label.SetReqSizeLabel(opts.ReqSizeLabel)
+		parser := parsers.NewLTSVParser(r, label, opts.QueryString)
+		parser.SetFields(fields)
+		return parser, nil
+	case "json":
+		keys := parsers.NewJSONKeys(opts.UriLabel, opts.ApptimeLabel, opts.ReqtimeLabel,
+			opts.SizeLabel, opts.StatusLabel, opts.MethodLabel, opts.TimeLabel)
+		keys.SetReqSizeKey(opts.ReqSizeLabel)
+		parser := parsers.NewJSONParser(r, keys, opts.QueryString)
+		parser.SetFields(fields)
+		return parser, nil
+	case "regexp":
+		parser, err := parsers.NewRegexpParser(r, opts.Pattern, opts.QueryString)
+		if err != nil {
+			return nil, err
+		}
+		parser.SetFields(fields)
+		return parser, nil
+	}
+
+	return nil, fmt.Errorf("unknown parser: %s", opts.Parser)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tkuchiki/gohttpstats"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type checkOptions struct {
+	*logOptions
+	rules *string
+	stats *string
+	junit *string
+}
+
+func registerCheckFlags(cmd *kingpin.CmdClause) *checkOptions {
+	return &checkOptions{
+		logOptions: registerLogFlags(cmd),
+		rules:      cmd.Flag("rules", "YAML rules file with the thresholds to check").Required().ExistingFile(),
+		stats:      cmd.Flag("stats", "check a YAML file written by analyze --dump instead of a log").ExistingFile(),
+		junit:      cmd.Flag("junit", "write a JUnit XML report to this file").String(),
+	}
+}
+
+// errCheckFailed makes the command exit non-zero once the violations are reported.
+var errCheckFailed = fmt.Errorf("check failed")
+
+func runCheck(c *checkOptions) error {
+	rules, err := httpstats.LoadRulesFile(*c.rules)
+	if err != nil {
+		return err
+	}
+
+	opts, err := c.loadOptions()
+	if err != nil {
+		return err
+	}
+
+	var hs *httpstats.HTTPStats
+	if *c.stats != "" {
+		hs, err = loadStats(*c.stats, opts)
+	} else {
+		hs, err = analyzeLog(opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := hs.Check(rules)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range report.Violations() {
+		fmt.Println(v)
+	}
+
+	if *c.junit != "" {
+		f, err := os.Create(*c.junit)
+		if err != nil {
+			return err
+		}
+
+		err = report.WriteJUnit(f)
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		err = f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if report.Failed() {
+		return errCheckFailed
+	}
+
+	return nil
+}
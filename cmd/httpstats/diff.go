@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/tkuchiki/gohttpstats"
+	"github.com/tkuchiki/gohttpstats/options"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type diffOptions struct {
+	before    *string
+	after     *string
+	sort      *string
+	percent   *bool
+	format    *string
+	noHeaders *bool
+	metrics   *string
+}
+
+func registerDiffFlags(cmd *kingpin.CmdClause) *diffOptions {
+	return &diffOptions{
+		before:    cmd.Arg("before", "YAML file written by analyze --dump before the change").Required().ExistingFile(),
+		after:     cmd.Arg("after", "YAML file written by analyze --dump after the change").Required().ExistingFile(),
+		sort:      cmd.Flag("sort", "metric whose worst regression is printed first").Default("p99").String(),
+		percent:   cmd.Flag("percent", "sort by the percent change instead of the difference").Bool(),
+		format:    cmd.Flag("format", "output format: table, tsv, csv, markdown, json or ndjson").Default("table").Enum("table", "tsv", "csv", "markdown", "json", "ndjson"),
+		noHeaders: cmd.Flag("noheaders", "print without headers (TSV, CSV and Markdown)").Bool(),
+		metrics:   cmd.Flag("metrics", "compared columns (comma separated keys, default count,avg,p50,p90,p99,5xx_rate,avg_body)").String(),
+	}
+}
+
+func runDiff(d *diffOptions) error {
+	opts := stats_options.NewOptions()
+
+	before, err := loadStats(*d.before, opts)
+	if err != nil {
+		return err
+	}
+
+	after, err := loadStats(*d.after, opts)
+	if err != nil {
+		return err
+	}
+
+	po := httpstats.NewPrintOptions()
+	po.SetFormat(*d.format)
+	po.SetNoHeaders(*d.noHeaders)
+
+	diff := httpstats.Diff(before, after, po)
+
+	if metrics := stats_options.SplitCSV(*d.metrics); len(metrics) > 0 {
+		err = diff.SetMetrics(metrics)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = diff.Sort(*d.sort, *d.percent)
+	if err != nil {
+		return err
+	}
+
+	return diff.Print()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/tkuchiki/gohttpstats"
+	"github.com/tkuchiki/gohttpstats/options"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// clearScreen moves the cursor home and clears the terminal before a redraw.
+const clearScreen = "\x1b[H\x1b[2J"
+
+type followOptions struct {
+	*logOptions
+	*outputOptions
+	refresh   *time.Duration
+	reset     *time.Duration
+	window    *time.Duration
+	fromStart *bool
+}
+
+func registerFollowFlags(cmd *kingpin.CmdClause) *followOptions {
+	return &followOptions{
+		logOptions:    registerLogFlags(cmd),
+		outputOptions: registerOutputFlags(cmd),
+		refresh:       cmd.Flag("refresh", "redraw the stats at this interval").Default("1s").Duration(),
+		reset:         cmd.Flag("reset", "clear the stats at this interval (e.g. 1m)").Duration(),
+		window:        cmd.Flag("window", "only show the requests of this recent period (e.g. 5m)").Duration(),
+		fromStart:     cmd.Flag("from-start", "read the file from its start instead of its end").Bool(),
+	}
+}
+
+func (f *followOptions) loadOptions() (*stats_options.Options, error) {
+	opt, err := f.outputOptions.options()
+	if err != nil {
+		return nil, err
+	}
+
+	return f.logOptions.loadOptions(opt...)
+}
+
+func runFollow(f *followOptions) error {
+	opts, err := f.loadOptions()
+	if err != nil {
+		return err
+	}
+
+	if *f.refresh <= 0 {
+		return fmt.Errorf("refresh interval must be positive: %s", *f.refresh)
+	}
+	if *f.reset > 0 && *f.window > 0 {
+		return fmt.Errorf("--reset and --window cannot be used together")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	var r io.ReadCloser
+	if opts.File == "" || opts.File == "-" {
+		// a pipe already waits for more data
+		r = os.Stdin
+	} else {
+		r, err = httpstats.NewFollower(ctx, opts.File, *f.fromStart)
+		if err != nil {
+			return err
+		}
+	}
+	defer r.Close()
+
+	hs, parser, err := newStatsParser(r, opts)
+	if err != nil {
+		return err
+	}
+
+	ls := httpstats.NewLiveStats(hs)
+	switch {
+	case *f.reset > 0:
+		err = ls.SetReset(*f.reset)
+	case *f.window > 0:
+		err = ls.SetWindow(*f.window)
+	}
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ls.Aggregate(ctx, parser)
+		done <- err
+	}()
+
+	ticker := time.NewTicker(*f.refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Print(clearScreen)
+			err = ls.Print()
+			if err != nil {
+				return err
+			}
+		case <-sig:
+			// a read of stdin cannot be interrupted, so the final stats are printed without waiting for it
+			cancel()
+			fmt.Print(clearScreen)
+			return ls.Print()
+		case err = <-done:
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(clearScreen)
+			return ls.Print()
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var version = "0.1.0"
+
+var (
+	app = kingpin.New("httpstats", "Access log analyzer built on gohttpstats.")
+
+	analyzeCmd   = app.Command("analyze", "Aggregate an access log and print the stats.")
+	analyzeFlags = registerAnalyzeFlags(analyzeCmd)
+
+	mergeCmd   = app.Command("merge", "Merge the stats dumped by analyze --dump and print them.")
+	mergeFlags = registerMergeFlags(mergeCmd)
+
+	diffCmd   = app.Command("diff", "Compare the stats dumped by analyze --dump before and after a change.")
+	diffFlags = registerDiffFlags(diffCmd)
+
+	checkCmd   = app.Command("check", "Aggregate an access log and check its stats against threshold rules; exits non-zero on violations.")
+	checkFlags = registerCheckFlags(checkCmd)
+
+	followCmd   = app.Command("follow", "Follow a growing access log like tail -F and redraw its stats at an interval.")
+	followFlags = registerFollowFlags(followCmd)
+)
+
+func main() {
+	app.Version(version)
+	app.HelpFlag.Short('h')
+
+	var err error
+	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	case analyzeCmd.FullCommand():
+		err = runAnalyze(analyzeFlags)
+	case mergeCmd.FullCommand():
+		err = runMerge(mergeFlags)
+	case diffCmd.FullCommand():
+		err = runDiff(diffFlags)
+	case checkCmd.FullCommand():
+		err = runCheck(checkFlags)
+	case followCmd.FullCommand():
+		err = runFollow(followFlags)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+
+	"github.com/tkuchiki/gohttpstats"
+	"github.com/tkuchiki/gohttpstats/options"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type mergeOptions struct {
+	files       *[]string
+	sort        *string
+	reverse     *bool
+	format      *string
+	noHeaders   *bool
+	aggregates  *string
+	columns     *string
+	percentiles *string
+	dump        *string
+}
+
+func registerMergeFlags(cmd *kingpin.CmdClause) *mergeOptions {
+	return &mergeOptions{
+		files:       cmd.Arg("files", "YAML files written by analyze --dump").Required().ExistingFiles(),
+		sort:        cmd.Flag("sort", "sort key").String(),
+		reverse:     cmd.Flag("reverse", "reverse the sort order").Short('r').Bool(),
+		format:      cmd.Flag("format", "output format: table, tsv, csv, markdown, json or ndjson").Enum("", "table", "tsv", "csv", "markdown", "json", "ndjson"),
+		noHeaders:   cmd.Flag("noheaders", "print without headers (TSV, CSV and Markdown)").Bool(),
+		aggregates:  cmd.Flag("aggregates", "group the merged stats by these dimensions (comma separated, default those of the first file)").String(),
+		columns:     cmd.Flag("columns", "printed columns (comma separated keys, e.g. count,method,uri,p99,avg)").Short('o').String(),
+		percentiles: cmd.Flag("percentiles", "percentile columns (comma separated, e.g. 50,95,99.9)").String(),
+		dump:        cmd.Flag("dump", "write the merged stats to this YAML file instead of printing them").String(),
+	}
+}
+
+func (m *mergeOptions) loadOptions() (*stats_options.Options, error) {
+	percentiles, err := splitFloatCSV(*m.percentiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats_options.NewOptions(
+		stats_options.Sort(*m.sort),
+		stats_options.Reverse(*m.reverse),
+		stats_options.Format(*m.format),
+		stats_options.NoHeaders(*m.noHeaders),
+		stats_options.CSVAggregates(*m.aggregates),
+		stats_options.CSVColumns(*m.columns),
+		stats_options.Percentiles(percentiles),
+		stats_options.Dump(*m.dump),
+	), nil
+}
+
+func runMerge(m *mergeOptions) error {
+	opts, err := m.loadOptions()
+	if err != nil {
+		return err
+	}
+
+	first, err := loadStats((*m.files)[0], opts)
+	if err != nil {
+		return err
+	}
+
+	// the dumps carry their aggregates, percentiles and limit;
+	// --aggregates and --percentiles override them
+	if len(opts.Aggregates) == 0 {
+		opts.Aggregates = first.Aggregates()
+	}
+	if len(opts.Percentiles) == 0 {
+		opts.Percentiles = first.Percentiles()
+	}
+	opts.Limit = first.Limit()
+	opts.OverflowUri = first.OverflowURI()
+
+	hs, err := newHTTPStats(opts)
+	if err != nil {
+		return err
+	}
+
+	err = hs.Merge(first)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range (*m.files)[1:] {
+		loaded, err := loadStats(file, opts)
+		if err != nil {
+			return err
+		}
+
+		err = hs.Merge(loaded)
+		if err != nil {
+			return err
+		}
+	}
+
+	hs.SortWithOptions()
+	if opts.Dump != "" {
+		return dumpStats(hs, opts.Dump)
+	}
+
+	return hs.Print()
+}
+
+// loadStats loads a file written by DumpStats. The entries of a dump in
+// the older list format are keyed by the aggregates of opts.
+func loadStats(file string, opts *stats_options.Options) (*httpstats.HTTPStats, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hs := httpstats.NewHTTPStats(true, false, false, httpstats.NewPrintOptions())
+	hs.SetAggregates(opts.Aggregates)
+
+	err = hs.LoadStats(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return hs, nil
+}
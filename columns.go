@@ -0,0 +1,173 @@
+package httpstats
+
+import (
+	"strconv"
+	"strings"
+)
+
+// column is a printable and sortable field of an httpStat, selected by key.
+// Text columns set text; numeric columns set value.
+type column struct {
+	header  string
+	text    func(s *httpStat) string
+	value   func(s *httpStat) float64
+	integer bool
+	// percent prints a ratio as a percentage
+	percent bool
+	// higherIsBetter marks metrics, such as throughput, whose decrease is a regression
+	higherIsBetter bool
+}
+
+func (c *column) format(s *httpStat) string {
+	if c.text != nil {
+		return c.text(s)
+	}
+
+	return c.formatValue(c.value(s))
+}
+
+// formatValue formats a value of a numeric column.
+func (c *column) formatValue(v float64) string {
+	if c.integer {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+
+	if c.percent {
+		return strconv.FormatFloat(v*100, 'f', 2, 64)
+	}
+
+	return round(v)
+}
+
+func (c *column) less(a, b *httpStat) bool {
+	if c.text != nil {
+		return c.text(a) < c.text(b)
+	}
+
+	return c.value(a) < c.value(b)
+}
+
+var columns = map[string]*column{
+	"count":           {header: "Count", integer: true, value: func(s *httpStat) float64 { return float64(s.Count()) }},
+	"method":          {header: "Method", text: func(s *httpStat) string { return s.Method }},
+	"operation_id":    {header: "OperationId", text: func(s *httpStat) string { return s.OperationID }},
+	"uri":             {header: "Uri", text: func(s *httpStat) string { return s.Uri }},
+	"status_1xx":      {header: "1xx", integer: true, value: func(s *httpStat) float64 { return float64(s.Status1xx) }},
+	"status_2xx":      {header: "2xx", integer: true, value: func(s *httpStat) float64 { return float64(s.Status2xx) }},
+	"status_3xx":      {header: "3xx", integer: true, value: func(s *httpStat) float64 { return float64(s.Status3xx) }},
+	"status_4xx":      {header: "4xx", integer: true, value: func(s *httpStat) float64 { return float64(s.Status4xx) }},
+	"status_5xx":      {header: "5xx", integer: true, value: func(s *httpStat) float64 { return float64(s.Status5xx) }},
+	"status_invalid":  {header: "Invalid", integer: true, value: func(s *httpStat) float64 { return float64(s.StatusInvalid) }},
+	"rps":             {header: "Req/s", higherIsBetter: true, value: (*httpStat).Throughput},
+	"4xx_rate":        {header: "4xx(%)", percent: true, value: (*httpStat).ErrorRate4xx},
+	"5xx_rate":        {header: "5xx(%)", percent: true, value: (*httpStat).ErrorRate5xx},
+	"apdex":           {header: "Apdex", higherIsBetter: true, value: (*httpStat).ApdexScore},
+	"min":             {header: "Min", value: (*httpStat).MinResponseTime},
+	"max":             {header: "Max", value: (*httpStat).MaxResponseTime},
+	"sum":             {header: "Sum", value: (*httpStat).SumResponseTime},
+	"avg":             {header: "Avg", value: (*httpStat).AvgResponseTime},
+	"stddev":          {header: "Stddev", value: (*httpStat).StddevResponseTime},
+	"min_body":        {header: "Min(Body)", value: (*httpStat).MinResponseBodySize},
+	"max_body":        {header: "Max(Body)", value: (*httpStat).MaxResponseBodySize},
+	"sum_body":        {header: "Sum(Body)", value: (*httpStat).SumResponseBodySize},
+	"avg_body":        {header: "Avg(Body)", value: (*httpStat).AvgResponseBodySize},
+	"stddev_body":     {header: "Stddev(Body)", value: (*httpStat).StddevResponseBodySize},
+	"min_req_body":    {header: "Min(ReqBody)", value: (*httpStat).MinRequestBodySize},
+	"max_req_body":    {header: "Max(ReqBody)", value: (*httpStat).MaxRequestBodySize},
+	"sum_req_body":    {header: "Sum(ReqBody)", value: (*httpStat).SumRequestBodySize},
+	"avg_req_body":    {header: "Avg(ReqBody)", value: (*httpStat).AvgRequestBodySize},
+	"stddev_req_body": {header: "Stddev(ReqBody)", value: (*httpStat).StddevRequestBodySize},
+}
+
+// lookupColumn returns the column for key: one of the keys of columns,
+// a response time percentile key such as "p99.9"
+// or an exact status key such as "status_499".
+func lookupColumn(key string) (*column, bool) {
+	if c, ok := columns[key]; ok {
+		return c, true
+	}
+
+	if p, ok := ParsePercentileKey(key); ok {
+		return &column{
+			header: strings.ToUpper(PercentileKey(p)),
+			value: func(s *httpStat) float64 {
+				return s.PercentileResponseTime(p)
+			},
+		}, true
+	}
+
+	if status, ok := parseStatusKey(key); ok {
+		return &column{
+			header:  strconv.Itoa(status),
+			integer: true,
+			value: func(s *httpStat) float64 {
+				return float64(s.CountStatus(status))
+			},
+		}, true
+	}
+
+	return nil, false
+}
+
+// parseStatusKey parses an exact status column key such as "status_499".
+func parseStatusKey(key string) (int, bool) {
+	if !strings.HasPrefix(key, "status_") {
+		return 0, false
+	}
+
+	status, err := strconv.Atoi(strings.TrimPrefix(key, "status_"))
+	if err != nil || status < 100 || status > 599 {
+		return 0, false
+	}
+
+	return status, true
+}
+
+// DefaultColumns returns the keys of the columns printed when none are
+// selected, with one response time column for each of percentiles.
+func DefaultColumns(percentiles []float64) []string {
+	return defaultColumns(DefaultAggregates, percentiles)
+}
+
+// defaultColumns returns the default columns with the method and uri
+// columns replaced by the aggregates.
+func defaultColumns(aggregates []string, percentiles []float64) []string {
+	keys := append([]string{"count"}, aggregates...)
+	keys = append(keys, "status_1xx", "status_2xx", "status_3xx", "status_4xx", "status_5xx",
+		"min", "max", "sum", "avg")
+
+	for _, p := range percentiles {
+		keys = append(keys, PercentileKey(p))
+	}
+
+	return append(keys, "stddev", "min_body", "max_body", "sum_body", "avg_body")
+}
+
+// lookupColumn also accepts the aggregated dimensions as column keys.
+func (hs *HTTPStats) lookupColumn(key string) (*column, bool) {
+	if c, ok := lookupColumn(key); ok {
+		return c, true
+	}
+
+	if !hs.aggregatesBy(key) {
+		return nil, false
+	}
+
+	return &column{
+		header: dimensionHeader(key),
+		text: func(s *httpStat) string {
+			return s.Dimensions[key]
+		},
+	}, true
+}
+
+func dimensionHeader(dimension string) string {
+	switch dimension {
+	case DimensionStatus:
+		return "Status"
+	case DimensionUserAgentClass:
+		return "UAClass"
+	}
+
+	return dimension
+}
@@ -0,0 +1,276 @@
+package httpstats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Statuses of the entries of a StatsDiff.
+const (
+	DiffChanged     = "changed"
+	DiffAppeared    = "appeared"
+	DiffDisappeared = "disappeared"
+)
+
+// DefaultDiffMetrics are the columns compared by a StatsDiff by default.
+var DefaultDiffMetrics = []string{"count", "avg", "p50", "p90", "p99", "5xx_rate", "avg_body"}
+
+// StatsDiff compares the entries of two HTTPStats, such as the stats
+// before and after a deploy, matched by the aggregates of the latter.
+type StatsDiff struct {
+	after        *HTTPStats
+	entries      []*diffEntry
+	metrics      []string
+	printOptions *PrintOptions
+}
+
+// diffEntry is an entry in before, after or both.
+type diffEntry struct {
+	before *httpStat
+	after  *httpStat
+}
+
+// stat returns the entry that identifies e.
+func (e *diffEntry) stat() *httpStat {
+	if e.after != nil {
+		return e.after
+	}
+
+	return e.before
+}
+
+func (e *diffEntry) status() string {
+	switch {
+	case e.before == nil:
+		return DiffAppeared
+	case e.after == nil:
+		return DiffDisappeared
+	}
+
+	return DiffChanged
+}
+
+// Diff compares before with after. The entries are in the order of after,
+// followed by those that disappeared, and are printed with po.
+func Diff(before, after *HTTPStats, po *PrintOptions) *StatsDiff {
+	aggregates := after.Aggregates()
+
+	beforeByKey := make(map[string]*httpStat, len(before.stats))
+	for _, s := range before.stats {
+		beforeByKey[s.key(aggregates)] = s
+	}
+
+	entries := make([]*diffEntry, 0, len(after.stats))
+	for _, s := range after.stats {
+		key := s.key(aggregates)
+		entries = append(entries, &diffEntry{before: beforeByKey[key], after: s})
+		delete(beforeByKey, key)
+	}
+
+	for _, s := range before.stats {
+		if _, ok := beforeByKey[s.key(aggregates)]; ok {
+			entries = append(entries, &diffEntry{before: s})
+		}
+	}
+
+	return &StatsDiff{
+		after:        after,
+		entries:      entries,
+		metrics:      DefaultDiffMetrics,
+		printOptions: po,
+	}
+}
+
+// SetMetrics selects the compared numeric columns by key, e.g. "p99" or "5xx_rate".
+func (d *StatsDiff) SetMetrics(keys []string) error {
+	for _, key := range keys {
+		c, ok := lookupColumn(key)
+		if !ok || c.value == nil {
+			return fmt.Errorf("unknown metric: %s", key)
+		}
+	}
+
+	d.metrics = keys
+
+	return nil
+}
+
+// metricDiff is the change of a metric of an entry. A missing entry has
+// the value 0, and Percent is NaN when the value before is 0.
+type metricDiff struct {
+	Before  float64
+	After   float64
+	Delta   float64
+	Percent float64
+}
+
+func (e *diffEntry) metric(c *column) *metricDiff {
+	m := &metricDiff{}
+	if e.before != nil {
+		m.Before = c.value(e.before)
+	}
+	if e.after != nil {
+		m.After = c.value(e.after)
+	}
+
+	m.Delta = m.After - m.Before
+	if m.Before != 0 {
+		m.Percent = m.Delta / m.Before * 100
+	} else {
+		m.Percent = math.NaN()
+	}
+
+	return m
+}
+
+// Sort orders the entries by the change of metric, the worst regression,
+// such as the largest latency increase or throughput decrease, first;
+// by percent when percent is set. Entries whose percent change is
+// undefined come last.
+func (d *StatsDiff) Sort(metric string, percent bool) error {
+	c, ok := lookupColumn(metric)
+	if !ok || c.value == nil {
+		return fmt.Errorf("unknown metric: %s", metric)
+	}
+
+	sign := 1.0
+	if c.higherIsBetter {
+		sign = -1.0
+	}
+
+	sort.SliceStable(d.entries, func(i, j int) bool {
+		a, b := d.entries[i].metric(c), d.entries[j].metric(c)
+		if !percent {
+			return sign*a.Delta > sign*b.Delta
+		}
+
+		if math.IsNaN(b.Percent) {
+			return !math.IsNaN(a.Percent)
+		}
+
+		return sign*a.Percent > sign*b.Percent
+	})
+
+	return nil
+}
+
+func (d *StatsDiff) Print() error {
+	return d.printOptions.print(d)
+}
+
+// keyColumns returns the columns that identify an entry.
+func (d *StatsDiff) keyColumns() []*column {
+	aggregates := d.after.Aggregates()
+	cols := make([]*column, 0, len(aggregates))
+	for _, key := range aggregates {
+		c, _ := d.after.lookupColumn(key)
+		cols = append(cols, c)
+	}
+
+	return cols
+}
+
+func (d *StatsDiff) metricColumns() []*column {
+	cols := make([]*column, 0, len(d.metrics))
+	for _, key := range d.metrics {
+		c, _ := lookupColumn(key)
+		cols = append(cols, c)
+	}
+
+	return cols
+}
+
+func (d *StatsDiff) headers() []string {
+	headers := []string{"Status"}
+	for _, c := range d.keyColumns() {
+		headers = append(headers, c.header)
+	}
+
+	for _, c := range d.metricColumns() {
+		headers = append(headers, c.header+"(Before)", c.header+"(After)", c.header+"(Diff)", c.header+"(Diff%)")
+	}
+
+	return headers
+}
+
+func (d *StatsDiff) rows() [][]string {
+	keyCols := d.keyColumns()
+	metricCols := d.metricColumns()
+
+	rows := make([][]string, 0, len(d.entries))
+	for _, e := range d.entries {
+		row := []string{e.status()}
+		for _, c := range keyCols {
+			row = append(row, c.format(e.stat()))
+		}
+
+		for _, c := range metricCols {
+			m := e.metric(c)
+			row = append(row, c.formatValue(m.Before), c.formatValue(m.After), c.formatValue(m.Delta), formatPercentChange(m.Percent))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func formatPercentChange(p float64) string {
+	if math.IsNaN(p) {
+		return "-"
+	}
+
+	return strconv.FormatFloat(p, 'f', 2, 64)
+}
+
+// jsonDiff is the JSON representation of a diffEntry.
+type jsonDiff struct {
+	Status     string                     `json:"status"`
+	Method     string                     `json:"method"`
+	Uri        string                     `json:"uri"`
+	Dimensions map[string]string          `json:"dimensions,omitempty"`
+	Metrics    map[string]*jsonMetricDiff `json:"metrics"`
+}
+
+// jsonMetricDiff is a metricDiff whose undefined percent is null.
+type jsonMetricDiff struct {
+	Before  float64  `json:"before"`
+	After   float64  `json:"after"`
+	Delta   float64  `json:"delta"`
+	Percent *float64 `json:"percent"`
+}
+
+func (d *StatsDiff) values() []interface{} {
+	values := make([]interface{}, 0, len(d.entries))
+	for _, e := range d.entries {
+		s := e.stat()
+		v := &jsonDiff{
+			Status:     e.status(),
+			Method:     s.Method,
+			Uri:        s.Uri,
+			Dimensions: s.Dimensions,
+			Metrics:    make(map[string]*jsonMetricDiff, len(d.metrics)),
+		}
+
+		for _, key := range d.metrics {
+			c, _ := lookupColumn(key)
+			m := e.metric(c)
+			jm := &jsonMetricDiff{
+				Before: m.Before,
+				After:  m.After,
+				Delta:  m.Delta,
+			}
+			if !math.IsNaN(m.Percent) {
+				percent := m.Percent
+				jm.Percent = &percent
+			}
+			v.Metrics[key] = jm
+		}
+
+		values = append(values, v)
+	}
+
+	return values
+}
@@ -0,0 +1,70 @@
+package httpstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := NewHTTPStats(true, false, false, NewPrintOptions())
+	before.Set("/foo", "GET", 200, 0.1, 10, 0)
+	before.Set("/foo", "GET", 200, 0.3, 10, 0)
+	before.Set("/bar", "GET", 200, 0.2, 10, 0)
+	before.Set("/old", "GET", 200, 0.2, 10, 0)
+
+	after := NewHTTPStats(true, false, false, NewPrintOptions())
+	after.Set("/foo", "GET", 200, 0.2, 10, 0)
+	after.Set("/bar", "GET", 500, 0.1, 10, 0)
+	after.Set("/new", "GET", 200, 0.5, 10, 0)
+
+	var buf bytes.Buffer
+	po := NewPrintOptions()
+	po.SetFormat("tsv")
+	po.SetWriter(&buf)
+
+	d := Diff(before, after, po)
+	assert.Nil(t, d.SetMetrics([]string{"count", "p99", "5xx_rate"}))
+	assert.NotNil(t, d.SetMetrics([]string{"uri"}))
+	assert.Nil(t, d.Sort("p99", false))
+
+	d.Print()
+	assert.Equal(t, "Status\tMethod\tUri\tCount(Before)\tCount(After)\tCount(Diff)\tCount(Diff%)\tP99(Before)\tP99(After)\tP99(Diff)\tP99(Diff%)\t5xx(%)(Before)\t5xx(%)(After)\t5xx(%)(Diff)\t5xx(%)(Diff%)\n"+
+		"appeared\tGET\t/new\t0\t1\t1\t-\t0.000\t0.500\t0.500\t-\t0.00\t0.00\t0.00\t-\n"+
+		"changed\tGET\t/foo\t2\t1\t-1\t-50.00\t0.300\t0.200\t-0.100\t-33.33\t0.00\t0.00\t0.00\t-\n"+
+		"changed\tGET\t/bar\t1\t1\t0\t0.00\t0.200\t0.100\t-0.100\t-50.00\t0.00\t100.00\t100.00\t-\n"+
+		"disappeared\tGET\t/old\t1\t0\t-1\t-100.00\t0.200\t0.000\t-0.200\t-100.00\t0.00\t0.00\t0.00\t-\n", buf.String())
+
+	buf.Reset()
+	po.SetFormat("json")
+	assert.Nil(t, d.Sort("5xx_rate", false))
+	d.Print()
+
+	var diffs []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &diffs))
+	assert.Equal(t, "/bar", diffs[0]["uri"])
+	metrics := diffs[0]["metrics"].(map[string]interface{})
+	assert.Equal(t, float64(1), metrics["5xx_rate"].(map[string]interface{})["delta"])
+	assert.Nil(t, metrics["5xx_rate"].(map[string]interface{})["percent"])
+}
+
+func TestDiffSortHigherIsBetter(t *testing.T) {
+	before := NewHTTPStats(true, false, false, NewPrintOptions())
+	assert.Nil(t, before.SetApdexThreshold(0.5))
+	before.Set("/faster", "GET", 200, 3.0, 10, 0)
+	before.Set("/slower", "GET", 200, 0.1, 10, 0)
+
+	after := NewHTTPStats(true, false, false, NewPrintOptions())
+	assert.Nil(t, after.SetApdexThreshold(0.5))
+	after.Set("/faster", "GET", 200, 0.1, 10, 0)
+	after.Set("/slower", "GET", 200, 3.0, 10, 0)
+
+	d := Diff(before, after, NewPrintOptions())
+	for _, percent := range []bool{false, true} {
+		assert.Nil(t, d.Sort("apdex", percent))
+		assert.Equal(t, "/slower", d.entries[0].stat().Uri)
+		assert.Equal(t, "/faster", d.entries[1].stat().Uri)
+	}
+}
@@ -0,0 +1,173 @@
+package httpstats
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Built-in dimensions accepted by SetAggregates.
+// Any other dimension is the name of a log field, such as "host" or "upstream".
+const (
+	DimensionUri            = "uri"
+	DimensionMethod         = "method"
+	DimensionStatus         = "status"
+	DimensionUserAgentClass = "ua_class"
+
+	DefaultUserAgentField = "ua"
+
+	// the overflow entry has no dimensions, so it gets a key no entry can have
+	overflowKey = "\x00overflow"
+)
+
+// DefaultAggregates groups the requests by method and uri.
+var DefaultAggregates = []string{DimensionMethod, DimensionUri}
+
+// User agent classes of the DimensionUserAgentClass dimension.
+const (
+	UserAgentBot     = "bot"
+	UserAgentMobile  = "mobile"
+	UserAgentBrowser = "browser"
+	UserAgentCLI     = "cli"
+	UserAgentOther   = "other"
+	UserAgentUnknown = "-"
+)
+
+var (
+	userAgentBotKeywords    = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit"}
+	userAgentMobileKeywords = []string{"mobile", "android", "iphone", "ipad"}
+	userAgentCLIKeywords    = []string{"curl/", "wget/", "python-requests/", "go-http-client/", "httpie/", "okhttp/"}
+)
+
+// classifyUserAgent returns the class of a User-Agent header value.
+func classifyUserAgent(ua string) string {
+	if ua == "" || ua == "-" {
+		return UserAgentUnknown
+	}
+
+	ua = strings.ToLower(ua)
+	switch {
+	case containsAny(ua, userAgentBotKeywords):
+		return UserAgentBot
+	case containsAny(ua, userAgentCLIKeywords):
+		return UserAgentCLI
+	case containsAny(ua, userAgentMobileKeywords):
+		return UserAgentMobile
+	case strings.HasPrefix(ua, "mozilla/"), strings.HasPrefix(ua, "opera/"):
+		return UserAgentBrowser
+	}
+
+	return UserAgentOther
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetAggregates groups the requests by the given dimensions, in order:
+// DimensionUri, DimensionMethod, DimensionStatus, DimensionUserAgentClass
+// or the name of a log field. The default is DefaultAggregates.
+// Entries that are not grouped by uri or method leave them empty.
+func (hs *HTTPStats) SetAggregates(dimensions []string) {
+	hs.aggregates = dimensions
+}
+
+func (hs *HTTPStats) Aggregates() []string {
+	if len(hs.aggregates) == 0 {
+		return DefaultAggregates
+	}
+
+	return hs.aggregates
+}
+
+// SetUserAgentField sets the log field DimensionUserAgentClass is derived from.
+func (hs *HTTPStats) SetUserAgentField(field string) {
+	hs.userAgentField = field
+}
+
+// Fields returns the log fields the parser must extract
+// into parsers.HTTPStat.Fields for the aggregates.
+func (hs *HTTPStats) Fields() []string {
+	var fields []string
+	for _, d := range hs.Aggregates() {
+		switch d {
+		case DimensionUri, DimensionMethod, DimensionStatus:
+		case DimensionUserAgentClass:
+			fields = append(fields, hs.userAgentFieldOrDefault())
+		default:
+			fields = append(fields, d)
+		}
+	}
+
+	return fields
+}
+
+func (hs *HTTPStats) userAgentFieldOrDefault() string {
+	if hs.userAgentField == "" {
+		return DefaultUserAgentField
+	}
+
+	return hs.userAgentField
+}
+
+func (hs *HTTPStats) aggregatesBy(dimension string) bool {
+	for _, d := range hs.Aggregates() {
+		if d == dimension {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dimensions returns the values of the aggregates other than uri and method.
+func (hs *HTTPStats) dimensions(status int, fields map[string]string) map[string]string {
+	var dims map[string]string
+	for _, d := range hs.Aggregates() {
+		var v string
+		switch d {
+		case DimensionUri, DimensionMethod:
+			continue
+		case DimensionStatus:
+			v = strconv.Itoa(status)
+		case DimensionUserAgentClass:
+			v = classifyUserAgent(fields[hs.userAgentFieldOrDefault()])
+		default:
+			v = fields[d]
+		}
+
+		if dims == nil {
+			dims = make(map[string]string)
+		}
+		dims[d] = v
+	}
+
+	return dims
+}
+
+// key returns the key that identifies s among the entries
+// grouped by aggregates.
+func (s *httpStat) key(aggregates []string) string {
+	return entryKey(aggregates, s.Uri, s.Method, s.Dimensions)
+}
+
+func entryKey(aggregates []string, uri, method string, dims map[string]string) string {
+	values := make([]string, 0, len(aggregates))
+	for _, d := range aggregates {
+		switch d {
+		case DimensionUri:
+			values = append(values, uri)
+		case DimensionMethod:
+			values = append(values, method)
+		default:
+			values = append(values, dims[d])
+		}
+	}
+
+	return strings.Join(values, "\t")
+}
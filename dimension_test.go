@@ -0,0 +1,80 @@
+package httpstats
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tkuchiki/gohttpstats/parsers"
+)
+
+func TestAggregates(t *testing.T) {
+	hs := NewHTTPStats(false, false, false, NewPrintOptions())
+	hs.SetAggregates([]string{"host", DimensionStatus, DimensionUserAgentClass})
+
+	assert.Equal(t, []string{"host", "ua"}, hs.Fields())
+
+	hs.SetWithFields("/foo", "GET", 200, 0.1, 0, 0, map[string]string{"host": "a.example.com", "ua": "curl/7.61.0"})
+	hs.SetWithFields("/bar", "POST", 200, 0.2, 0, 0, map[string]string{"host": "a.example.com", "ua": "curl/8.0.1"})
+	hs.SetWithFields("/foo", "GET", 404, 0.3, 0, 0, map[string]string{"host": "a.example.com", "ua": "Googlebot/2.1"})
+	hs.SetWithFields("/foo", "GET", 200, 0.4, 0, 0, map[string]string{"host": "b.example.com", "ua": "Mozilla/5.0 (X11; Linux x86_64)"})
+
+	assert.Equal(t, 3, hs.CountUris())
+
+	s := hs.Stats()[0]
+	assert.Equal(t, 2, s.Count())
+	assert.Equal(t, "", s.Uri)
+	assert.Equal(t, "", s.Method)
+	assert.Equal(t, map[string]string{"host": "a.example.com", "status": "200", "ua_class": UserAgentCLI}, s.Dimensions)
+
+	var buf bytes.Buffer
+	hs.printOptions.SetWriter(&buf)
+	hs.printOptions.SetFormat("tsv")
+	assert.Nil(t, hs.SetColumns([]string{"host", "status", "ua_class", "count"}))
+	hs.Print()
+
+	assert.Equal(t, "host\tStatus\tUAClass\tCount\n"+
+		"a.example.com\t200\tcli\t2\n"+
+		"a.example.com\t404\tbot\t1\n"+
+		"b.example.com\t200\tbrowser\t1\n", buf.String())
+
+	assert.NotNil(t, hs.SetColumns([]string{"upstream"}))
+}
+
+func TestAggregateUserAgentClassOfPreset(t *testing.T) {
+	data := bytes.NewBufferString(`10.0.0.1 - - [14/Oct/2018:05:58:05 +0900] "GET /foo HTTP/1.1" 200 153 "-" "curl/7.61.0"
+10.0.0.2 - - [14/Oct/2018:05:58:06 +0900] "GET /foo HTTP/1.1" 200 153 "-" "Googlebot/2.1"
+`)
+
+	hs := NewHTTPStats(false, false, false, NewPrintOptions())
+	hs.SetAggregates([]string{DimensionUserAgentClass})
+	hs.SetUserAgentField(parsers.RegexpUserAgentField("combined"))
+
+	parser, err := parsers.NewRegexpParser(data, "combined", false)
+	assert.Nil(t, err)
+	parser.SetFields(hs.Fields())
+
+	_, err = hs.Aggregate(context.Background(), parser)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, hs.CountUris())
+	assert.Equal(t, UserAgentCLI, hs.Stats()[0].Dimensions[DimensionUserAgentClass])
+	assert.Equal(t, UserAgentBot, hs.Stats()[1].Dimensions[DimensionUserAgentClass])
+}
+
+func TestClassifyUserAgent(t *testing.T) {
+	tests := map[string]string{
+		"":  UserAgentUnknown,
+		"-": UserAgentUnknown,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":                                      UserAgentBot,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148": UserAgentMobile,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36":   UserAgentBrowser,
+		"python-requests/2.31.0": UserAgentCLI,
+		"SomeService/1.0":        UserAgentOther,
+	}
+
+	for ua, want := range tests {
+		assert.Equal(t, want, classifyUserAgent(ua), ua)
+	}
+}
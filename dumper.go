@@ -3,10 +3,79 @@ package httpstats
 import (
 	"gopkg.in/yaml.v2"
 	"io"
+
+	"github.com/tkuchiki/gohttpstats/options"
 )
 
+// dumpVersion is the version of the dump format written by DumpStats.
+// Version 1 was a bare list of entries, which LoadStats still reads.
+const dumpVersion = 2
+
+// statsDump is the dump of an HTTPStats: its entries together with the
+// configuration needed to keep aggregating into them after LoadStats.
+type statsDump struct {
+	Version                       int                    `yaml:"version"`
+	Options                       *stats_options.Options `yaml:"options,omitempty"`
+	Aggregates                    []string               `yaml:"aggregates,omitempty"`
+	UserAgentField                string                 `yaml:"user_agent_field,omitempty"`
+	UseResponseTimePercentile     bool                   `yaml:"use_response_time_percentile"`
+	UseRequestBodySizePercentile  bool                   `yaml:"use_request_body_size_percentile"`
+	UseResponseBodySizePercentile bool                   `yaml:"use_response_body_size_percentile"`
+	SketchAccuracy                float64                `yaml:"sketch_accuracy,omitempty"`
+	Percentiles                   []float64              `yaml:"percentiles"`
+	URIGroups                     []*uriGroupDump        `yaml:"uri_groups,omitempty"`
+	URINormalizers                []string               `yaml:"uri_normalizers,omitempty"`
+	UnmatchedUri                  string                 `yaml:"unmatched_uri,omitempty"`
+	Limit                         int                    `yaml:"limit,omitempty"`
+	OverflowUri                   string                 `yaml:"overflow_uri,omitempty"`
+	OverflowCount                 int                    `yaml:"overflow_count,omitempty"`
+	ApdexThreshold                float64                `yaml:"apdex_threshold,omitempty"`
+	Stats                         httpStats              `yaml:"stats"`
+}
+
+type uriGroupDump struct {
+	Pattern        string  `yaml:"pattern"`
+	Template       string  `yaml:"template"`
+	Method         string  `yaml:"method,omitempty"`
+	OperationID    string  `yaml:"operation_id,omitempty"`
+	ApdexThreshold float64 `yaml:"apdex_threshold,omitempty"`
+}
+
+// DumpStats writes the entries and the configuration of hs as YAML.
 func (hs *HTTPStats) DumpStats(w io.Writer) error {
-	buf, err := yaml.Marshal(&hs.stats)
+	dump := &statsDump{
+		Version:                       dumpVersion,
+		Options:                       hs.options,
+		Aggregates:                    hs.aggregates,
+		UserAgentField:                hs.userAgentField,
+		UseResponseTimePercentile:     hs.useResponseTimePercentile,
+		UseRequestBodySizePercentile:  hs.useRequestBodySizePercentile,
+		UseResponseBodySizePercentile: hs.useResponseBodySizePercentile,
+		SketchAccuracy:                hs.sketchAccuracy,
+		Percentiles:                   hs.percentiles,
+		UnmatchedUri:                  hs.unmatchedUri,
+		Limit:                         hs.limit,
+		OverflowUri:                   hs.overflowUri,
+		OverflowCount:                 hs.overflowCount,
+		ApdexThreshold:                hs.apdexThreshold,
+		Stats:                         hs.stats,
+	}
+
+	for _, g := range hs.uriCapturingGroups {
+		dump.URIGroups = append(dump.URIGroups, &uriGroupDump{
+			Pattern:        g.String(),
+			Template:       g.Template(),
+			Method:         g.Method(),
+			OperationID:    g.OperationID(),
+			ApdexThreshold: g.ApdexThreshold(),
+		})
+	}
+
+	for _, n := range hs.uriNormalizers {
+		dump.URINormalizers = append(dump.URINormalizers, n.name)
+	}
+
+	buf, err := yaml.Marshal(dump)
 	if err != nil {
 		return err
 	}
@@ -15,13 +15,24 @@ func TestDumpStats(t *testing.T) {
 	err := stats.DumpStats(outw)
 	assert.Nil(t, err)
 
-	data := bytes.NewBufferString(`- uri: /foo/bar
+	data := bytes.NewBufferString(`version: 2
+use_response_time_percentile: true
+use_request_body_size_percentile: false
+use_response_body_size_percentile: false
+percentiles:
+- 1
+- 50
+- 99
+stats:
+- uri: /foo/bar
   cnt: 1
   status1xx: 0
   status2xx: 1
   status3xx: 0
   status4xx: 0
   status5xx: 0
+  statuses:
+    200: 1
   method: POST
   responsetime:
     max: 0.057
@@ -29,14 +40,23 @@ func TestDumpStats(t *testing.T) {
     sum: 0.057
     percentiles:
     - 0.057
+    samples: 1
+    mean: 0.057
+    m2: 0
   requestbodysize:
-    max: 12
-    min: 12
-    sum: 12
-  responsebodysize:
     max: 0
     min: 0
     sum: 0
+    samples: 1
+    mean: 0
+    m2: 0
+  responsebodysize:
+    max: 12
+    min: 12
+    sum: 12
+    samples: 1
+    mean: 12
+    m2: 0
 `)
 
 	assert.Equal(t, data, outw)
@@ -1,7 +1,14 @@
 package httpstats
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/tkuchiki/gohttpstats/parsers"
+)
 
 var (
-	SkipReadLineErr = errors.New("Skip read line")
+	SkipReadLineErr = parsers.SkipReadLineErr
+	// InvalidTimeErr is returned for a request whose time cannot be parsed,
+	// which is counted without its time.
+	InvalidTimeErr = errors.New("invalid time")
 )
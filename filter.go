@@ -107,6 +107,22 @@ func (f *Filter) Do(uri, status, timestr string) error {
 		return nil
 	}
 
+	var t time.Time
+	var err error
+	if f.sTimeNano != 0 || f.eTimeNano != 0 {
+		t, err = f.ParseTime(timestr)
+	}
+
+	return f.doTime(uri, status, t, err)
+}
+
+// doTime is Do for a line whose time is already parsed, or failed to parse
+// with timeErr; a line without a time is outside any time range.
+func (f *Filter) doTime(uri, status string, t time.Time, timeErr error) error {
+	if !f.isEnable() {
+		return nil
+	}
+
 	if len(f.includeGroups) > 0 {
 		isnotMatched := true
 		for _, re := range f.includeGroups {
@@ -150,8 +166,7 @@ func (f *Filter) Do(uri, status, timestr string) error {
 	}
 
 	if f.sTimeNano != 0 || f.eTimeNano != 0 {
-		t, err := f.ParseTime(timestr)
-		if err != nil {
+		if timeErr != nil || t.IsZero() {
 			return SkipReadLineErr
 		}
 		timeNano := t.UnixNano()
@@ -0,0 +1,115 @@
+package httpstats
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+const defaultFollowPollInterval = 250 * time.Millisecond
+
+// Follower reads a growing file like tail -F. At the end of the file it
+// waits for more data instead of returning io.EOF, reopens the file when
+// it is renamed or replaced, e.g. by logrotate, and reads it again from
+// the start when it is truncated. Read returns io.EOF once ctx is done.
+type Follower struct {
+	ctx          context.Context
+	path         string
+	file         *os.File
+	offset       int64
+	pollInterval time.Duration
+}
+
+// NewFollower opens path and follows it from its end, or from its start when fromStart is set.
+func NewFollower(ctx context.Context, path string, fromStart bool) (*Follower, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var offset int64
+	if !fromStart {
+		offset, err = f.Seek(0, io.SeekEnd)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return &Follower{
+		ctx:          ctx,
+		path:         path,
+		file:         f,
+		offset:       offset,
+		pollInterval: defaultFollowPollInterval,
+	}, nil
+}
+
+func (f *Follower) Read(p []byte) (int, error) {
+	for {
+		n, err := f.file.Read(p)
+		f.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		// the rest of a rotated file is read before switching to the new one
+		err = f.reopen()
+		if err != nil {
+			return 0, err
+		}
+
+		select {
+		case <-f.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(f.pollInterval):
+		}
+	}
+}
+
+// reopen switches to the file at path when it is not the one being read,
+// and rewinds when the file was truncated. A missing path, between the
+// rename of a log and the creation of the next, is waited for.
+func (f *Follower) reopen() error {
+	info, err := os.Stat(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	current, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if !os.SameFile(info, current) {
+		file, err := os.Open(f.path)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		f.file.Close()
+		f.file = file
+		f.offset = 0
+
+		return nil
+	}
+
+	if current.Size() < f.offset {
+		f.offset, err = f.file.Seek(0, io.SeekStart)
+		return err
+	}
+
+	return nil
+}
+
+func (f *Follower) Close() error {
+	return f.file.Close()
+}
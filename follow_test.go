@@ -0,0 +1,66 @@
+package httpstats
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFollower(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpstats")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("old\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f, err := NewFollower(ctx, path, false)
+	assert.Nil(t, err)
+	defer f.Close()
+	f.pollInterval = time.Millisecond
+
+	r := bufio.NewReader(f)
+	appendLine := func(name, line string) {
+		w, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		assert.Nil(t, err)
+		_, err = w.WriteString(line)
+		assert.Nil(t, err)
+		assert.Nil(t, w.Close())
+	}
+
+	appendLine("access.log", "appended\n")
+	line, err := r.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "appended\n", line)
+
+	// truncated
+	assert.Nil(t, os.Truncate(path, 0))
+	appendLine("access.log", "new\n")
+	line, err = r.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "new\n", line)
+
+	// rotated: the rest of the old file comes before the new one
+	appendLine("access.log", "last\n")
+	assert.Nil(t, os.Rename(path, path+".1"))
+	appendLine("access.log", "rotated\n")
+	line, err = r.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "last\n", line)
+	line, err = r.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "rotated\n", line)
+
+	cancel()
+	_, err = r.ReadString('\n')
+	assert.Equal(t, "EOF", err.Error())
+}
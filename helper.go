@@ -5,17 +5,20 @@ import (
 	"strconv"
 )
 
+// CompileUriGroups returns the regexps of the uri groups compiled like
+// HTTPStats.SetURICapturingGroups, with route patterns converted to regexps.
 func CompileUriGroups(groups []string) ([]*regexp.Regexp, error) {
-	uriGroups := make([]*regexp.Regexp, 0, len(groups))
-	for _, pattern := range groups {
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			return []*regexp.Regexp{}, err
-		}
-		uriGroups = append(uriGroups, re)
+	uriGroups, err := compileURIGroups(groups)
+	if err != nil {
+		return []*regexp.Regexp{}, err
 	}
 
-	return uriGroups, nil
+	res := make([]*regexp.Regexp, 0, len(uriGroups))
+	for _, g := range uriGroups {
+		res = append(res, g.re)
+	}
+
+	return res, nil
 }
 
 func IsIncludedInTime(start, end, val int64) bool {
@@ -0,0 +1,158 @@
+package httpstats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tkuchiki/gohttpstats/parsers"
+)
+
+// liveWindowBuckets is the number of buckets of a sliding window.
+const liveWindowBuckets = 10
+
+// LiveStats aggregates requests as they are read, e.g. from a Follower,
+// while the stats are printed from another goroutine. By default it keeps
+// all requests; SetReset clears the stats periodically and SetWindow keeps
+// the requests of a recent period only. Times are those of the clock,
+// not of the log.
+type LiveStats struct {
+	mu         sync.Mutex
+	template   *HTTPStats
+	buckets    []*liveBucket
+	bucketSize time.Duration
+	window     time.Duration
+	now        func() time.Time
+}
+
+type liveBucket struct {
+	start time.Time
+	stats *HTTPStats
+}
+
+// NewLiveStats returns a LiveStats whose stats are configured like hs.
+func NewLiveStats(hs *HTTPStats) *LiveStats {
+	return &LiveStats{
+		template: hs,
+		now:      time.Now,
+	}
+}
+
+// SetReset clears the stats every d.
+func (ls *LiveStats) SetReset(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("reset interval must be positive: %s", d)
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.bucketSize = d
+	ls.window = d
+	ls.buckets = nil
+
+	return nil
+}
+
+// SetWindow keeps the requests of the last d, which slides in steps of a tenth of d.
+func (ls *LiveStats) SetWindow(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("window must be positive: %s", d)
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.bucketSize = d / liveWindowBuckets
+	ls.window = d
+	ls.buckets = nil
+
+	return nil
+}
+
+// bucket returns the bucket of now and drops the buckets out of the window.
+func (ls *LiveStats) bucket(now time.Time) *HTTPStats {
+	if ls.bucketSize == 0 {
+		if len(ls.buckets) == 0 {
+			ls.buckets = []*liveBucket{{stats: ls.template.emptyCopy()}}
+		}
+		return ls.buckets[0].stats
+	}
+
+	ls.expire(now)
+
+	start := now.Truncate(ls.bucketSize)
+	if n := len(ls.buckets); n > 0 && ls.buckets[n-1].start.Equal(start) {
+		return ls.buckets[n-1].stats
+	}
+
+	b := &liveBucket{start: start, stats: ls.template.emptyCopy()}
+	ls.buckets = append(ls.buckets, b)
+
+	return b.stats
+}
+
+func (ls *LiveStats) expire(now time.Time) {
+	if ls.bucketSize == 0 {
+		return
+	}
+
+	oldest := now.Truncate(ls.bucketSize).Add(-ls.window + ls.bucketSize)
+	i := 0
+	for i < len(ls.buckets) && ls.buckets[i].start.Before(oldest) {
+		i++
+	}
+	ls.buckets = ls.buckets[i:]
+}
+
+// SetHTTPStat is HTTPStats.SetHTTPStat for the current stats.
+func (ls *LiveStats) SetHTTPStat(stat *parsers.HTTPStat) error {
+	t, err := parseStatTime(ls.template.parseTime, stat.Time)
+	return ls.setHTTPStat(stat, t, err)
+}
+
+func (ls *LiveStats) setHTTPStat(stat *parsers.HTTPStat, t time.Time, timeErr error) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	return ls.bucket(ls.now()).setHTTPStat(stat, t, timeErr)
+}
+
+// Aggregate is HTTPStats.Aggregate for a LiveStats, with the filter of the
+// HTTPStats it was created from. It returns when the parser does, i.e. at
+// the end of the input, or when ctx is done.
+func (ls *LiveStats) Aggregate(ctx context.Context, parser parsers.Parser) (*AggregateResult, error) {
+	return aggregate(ctx, parser, ls.template.parseTime, ls.template.doFilterTime, ls.setHTTPStat)
+}
+
+// Snapshot returns a copy of the current stats.
+func (ls *LiveStats) Snapshot() (*HTTPStats, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.expire(ls.now())
+
+	hs := ls.template.emptyCopy()
+	for _, b := range ls.buckets {
+		err := hs.Merge(b.stats)
+		if err != nil {
+			return nil, err
+		}
+	}
+	hs.sortSamples()
+
+	return hs, nil
+}
+
+// Print prints a snapshot of the current stats, sorted with the options.
+func (ls *LiveStats) Print() error {
+	hs, err := ls.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	hs.SortWithOptions()
+
+	return hs.Print()
+}
@@ -0,0 +1,52 @@
+package httpstats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tkuchiki/gohttpstats/parsers"
+)
+
+func TestLiveStatsWindow(t *testing.T) {
+	ls := NewLiveStats(NewHTTPStats(true, false, false, NewPrintOptions()))
+	assert.Nil(t, ls.SetWindow(10*time.Second))
+
+	now := time.Date(2018, 10, 14, 5, 58, 0, 0, time.UTC)
+	ls.now = func() time.Time { return now }
+
+	ls.SetHTTPStat(&parsers.HTTPStat{Uri: "/foo", Method: "GET", Status: 200, ResponseTime: 0.1})
+	now = now.Add(5 * time.Second)
+	ls.SetHTTPStat(&parsers.HTTPStat{Uri: "/foo", Method: "GET", Status: 200, ResponseTime: 0.3})
+
+	hs, err := ls.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, hs.Stats()[0].Count())
+	assert.Equal(t, 0.3, hs.Stats()[0].MaxResponseTime())
+
+	// the first request slides out of the window
+	now = now.Add(5 * time.Second)
+	hs, err = ls.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, hs.Stats()[0].Count())
+	assert.Equal(t, 0.3, hs.Stats()[0].MinResponseTime())
+}
+
+func TestLiveStatsReset(t *testing.T) {
+	ls := NewLiveStats(NewHTTPStats(true, false, false, NewPrintOptions()))
+	assert.Nil(t, ls.SetReset(time.Minute))
+	assert.NotNil(t, ls.SetReset(0))
+
+	now := time.Date(2018, 10, 14, 5, 58, 30, 0, time.UTC)
+	ls.now = func() time.Time { return now }
+
+	ls.SetHTTPStat(&parsers.HTTPStat{Uri: "/foo", Method: "GET", Status: 200, ResponseTime: 0.1})
+	hs, err := ls.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, hs.CountUris())
+
+	now = now.Add(30 * time.Second)
+	hs, err = ls.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, hs.CountUris())
+}
@@ -1,21 +1,167 @@
 package httpstats
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 
 	"gopkg.in/yaml.v2"
 )
 
+// LoadStats replaces the entries and the configuration of hs with those
+// dumped by DumpStats, so that further requests and merged stats are
+// counted in the loaded entries. A dump of the older format, a bare list
+// of entries, only replaces the entries, which are keyed by the
+// aggregates of hs, and its summaries have no standard deviation.
 func (hs *HTTPStats) LoadStats(r io.Reader) error {
 	buf, err := ioutil.ReadAll(r)
 	if err != nil {
 		return err
 	}
 
-	var stats []*httpStat
-	err = yaml.Unmarshal(buf, &stats)
-	hs.stats = stats
+	var dump statsDump
+	err = yaml.Unmarshal(buf, &dump)
+	if err != nil {
+		var stats []*httpStat
+		if yaml.Unmarshal(buf, &stats) != nil {
+			return err
+		}
+
+		return hs.loadEntries(stats)
+	}
+
+	if dump.Version > dumpVersion {
+		return fmt.Errorf("unsupported dump version: %d", dump.Version)
+	}
+
+	err = hs.loadConfig(&dump)
+	if err != nil {
+		return err
+	}
+
+	err = hs.loadEntries(dump.Stats)
+	if err != nil {
+		return err
+	}
+
+	hs.overflowCount = dump.OverflowCount
+
+	return nil
+}
+
+func (hs *HTTPStats) loadConfig(dump *statsDump) error {
+	if dump.Options != nil {
+		hs.options = dump.Options
+		err := hs.InitFilter(dump.Options)
+		if err != nil {
+			return err
+		}
+	}
+
+	groups := make([]*URIGroup, 0, len(dump.URIGroups))
+	for _, gd := range dump.URIGroups {
+		g, err := NewURIGroup(gd.Pattern, gd.Template)
+		if err != nil {
+			return err
+		}
+		g.method = gd.Method
+		g.operationID = gd.OperationID
+		g.apdexThreshold = gd.ApdexThreshold
+		groups = append(groups, g)
+	}
+
+	normalizers, err := lookupURINormalizers(dump.URINormalizers)
+	if err != nil {
+		return err
+	}
+
+	hs.aggregates = dump.Aggregates
+	hs.userAgentField = dump.UserAgentField
+	hs.useResponseTimePercentile = dump.UseResponseTimePercentile
+	hs.useRequestBodySizePercentile = dump.UseRequestBodySizePercentile
+	hs.useResponseBodySizePercentile = dump.UseResponseBodySizePercentile
+	hs.sketchAccuracy = dump.SketchAccuracy
+	if len(dump.Percentiles) > 0 {
+		hs.percentiles = dump.Percentiles
+	}
+	hs.uriCapturingGroups = groups
+	hs.uriNormalizers = normalizers
+	hs.unmatchedUri = dump.UnmatchedUri
+	hs.limit = dump.Limit
+	hs.overflowUri = dump.OverflowUri
+	hs.apdexThreshold = dump.ApdexThreshold
+
+	return nil
+}
+
+// loadEntries replaces the entries of hs with stats,
+// merging the entries with the same key.
+func (hs *HTTPStats) loadEntries(stats []*httpStat) error {
+	hs.hints = newHints()
+	hs.stats = make([]*httpStat, 0, len(stats))
+	hs.overflowCount = 0
+
+	for _, s := range stats {
+		s.fillDefaults()
+		s.ResponseTime.fillSamples(s.Cnt, s.ResponseTime.Sum)
+		s.RequestBodySize.fillSamples(s.Cnt, s.RequestBodySize.Sum)
+		s.ResponseBodySize.fillSamples(s.Cnt, s.ResponseBodySize.Sum)
+		s.ResponseTime.usePercentile = hs.useResponseTimePercentile
+		s.RequestBodySize.usePercentile = hs.useRequestBodySizePercentile
+		s.ResponseBodySize.usePercentile = hs.useResponseBodySizePercentile
+
+		idx := hs.hints.loadOrStore(hs.loadedKey(s))
+		if idx < len(hs.stats) {
+			err := hs.stats[idx].merge(s)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		hs.stats = append(hs.stats, s)
+	}
+	hs.sortSamples()
+
+	return nil
+}
+
+// loadedKey returns the key of an entry of hs, which is the key of the
+// overflow entry for the entry Set counted beyond the limit.
+func (hs *HTTPStats) loadedKey(s *httpStat) string {
+	if hs.isOverflow(s) {
+		return overflowKey
+	}
+
+	return s.key(hs.Aggregates())
+}
+
+// isOverflow reports whether s is the overflow entry of hs.
+func (hs *HTTPStats) isOverflow(s *httpStat) bool {
+	return hs.overflowUri != "" && s.Uri == hs.overflowUri && s.Method == "" && len(s.Dimensions) == 0
+}
+
+// fillDefaults sets the summaries missing from a dump.
+func (s *httpStat) fillDefaults() {
+	if s.ResponseTime == nil {
+		s.ResponseTime = newResponseTime(false, 0)
+	}
+	if s.RequestBodySize == nil {
+		s.RequestBodySize = newBodySize(false, 0)
+	}
+	if s.ResponseBodySize == nil {
+		s.ResponseBodySize = newBodySize(false, 0)
+	}
+}
+
+// fillSamples counts the samples of a summary dumped before the running
+// statistics were, so that it is not taken for an empty one when merged.
+// Its standard deviation is unknown and taken as 0.
+func (w *welford) fillSamples(cnt int, sum float64) {
+	if w.N > 0 || cnt == 0 {
+		return
+	}
 
-	return err
+	w.N = cnt
+	w.Mean = sum / float64(cnt)
 }
@@ -2,8 +2,10 @@ package httpstats
 
 import (
 	"bytes"
-	"github.com/stretchr/testify/assert"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tkuchiki/gohttpstats/options"
 )
 
 func TestLoadStats(t *testing.T) {
@@ -48,4 +50,99 @@ func TestLoadStats(t *testing.T) {
 	assert.Equal(t, 0, s[0].Status3xx)
 	assert.Equal(t, 0, s[0].Status4xx)
 	assert.Equal(t, 0, s[0].Status5xx)
+
+	stats.Set("/foo/bar", "POST", 200, 0.1, 0, 0)
+	assert.Equal(t, 1, stats.CountUris())
+	assert.Equal(t, 2, s[0].Cnt)
+}
+
+func TestLoadStatsResumesAggregation(t *testing.T) {
+	po := NewPrintOptions()
+	stats := NewHTTPStats(true, false, false, po)
+	stats.SetOptions(stats_options.NewOptions(stats_options.Sort("max")))
+	assert.Nil(t, stats.SetURICapturingGroups([]string{"/users/:id"}))
+	assert.Nil(t, stats.SetURINormalizers([]string{NormalizeUUID}))
+	assert.Nil(t, stats.SetPercentiles([]float64{50, 95}))
+	stats.SetLimit(2, "OTHER")
+	stats.Set("/users/1", "GET", 200, 0.1, 0, 0)
+	stats.Set("/files/0b7d4b76-5c6f-4c4e-8b1b-4e6f2c8d9a01", "GET", 200, 0.2, 0, 0)
+	stats.Set("/other", "GET", 200, 0.3, 0, 0)
+
+	var buf bytes.Buffer
+	assert.Nil(t, stats.DumpStats(&buf))
+
+	loaded := NewHTTPStats(false, false, false, po)
+	assert.Nil(t, loaded.LoadStats(&buf))
+
+	assert.Equal(t, 3, loaded.CountUris())
+	assert.Equal(t, []float64{50, 95}, loaded.Percentiles())
+	assert.Equal(t, "max", loaded.options.Sort)
+	assert.Equal(t, 1, loaded.OverflowCount())
+
+	loaded.Set("/users/2", "GET", 200, 0.4, 0, 0)
+	loaded.Set("/files/3f2c1a9e-7b6d-4e5f-9a8b-1c2d3e4f5a6b", "GET", 200, 0.5, 0, 0)
+	loaded.Set("/another", "GET", 200, 0.6, 0, 0)
+
+	assert.Equal(t, 3, loaded.CountUris())
+	assert.Equal(t, 2, loaded.OverflowCount())
+
+	s := loaded.Stats()
+	assert.Equal(t, "/users/:id", s[0].Uri)
+	assert.Equal(t, 2, s[0].Cnt)
+	assert.Equal(t, 0.4, s[0].PercentileResponseTime(95))
+	assert.Equal(t, "/files/:uuid", s[1].Uri)
+	assert.Equal(t, 2, s[1].Cnt)
+	assert.Equal(t, "OTHER", s[2].Uri)
+	assert.Equal(t, 2, s[2].Cnt)
+}
+
+func TestLoadStatsBaselineDump(t *testing.T) {
+	data := bytes.NewBufferString(`- uri: /foo/bar
+  cnt: 3
+  status1xx: 0
+  status2xx: 3
+  status3xx: 0
+  status4xx: 0
+  status5xx: 0
+  method: GET
+  responsetime:
+    max: 0.3
+    min: 0.1
+    sum: 0.6
+    percentiles:
+    - 0.1
+    - 0.3
+    - 0.2
+  requestbodysize:
+    max: 0
+    min: 0
+    sum: 0
+  responsebodysize:
+    max: 30
+    min: 10
+    sum: 60`)
+
+	po := NewPrintOptions()
+	loaded := NewHTTPStats(true, false, false, po)
+	assert.Nil(t, loaded.LoadStats(data))
+
+	s := loaded.Stats()[0]
+	assert.Equal(t, 0.3, s.MaxResponseTime())
+	assert.Equal(t, 0.6, s.SumResponseTime())
+	assert.Equal(t, 0.3, s.P99ResponseTime())
+	assert.Equal(t, 0.2, s.P50ResponseTime())
+	assert.Equal(t, float64(30), s.MaxResponseBodySize())
+
+	// merged, as by the merge command
+	hs := NewHTTPStats(true, false, false, po)
+	assert.Nil(t, hs.Merge(loaded))
+	hs.Set("/foo/bar", "GET", 200, 0.05, 20, 0)
+
+	s = hs.Stats()[0]
+	assert.Equal(t, 4, s.Count())
+	assert.Equal(t, 0.3, s.MaxResponseTime())
+	assert.Equal(t, 0.05, s.MinResponseTime())
+	assert.Equal(t, float64(30), s.MaxResponseBodySize())
+	assert.Equal(t, float64(10), s.MinResponseBodySize())
+	assert.Equal(t, 0.3, s.P99ResponseTime())
 }
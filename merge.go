@@ -0,0 +1,201 @@
+package httpstats
+
+import (
+	"fmt"
+)
+
+// Merge adds the entries of other to hs, combining the entries with the
+// same key, so that hs holds the stats of the requests of both.
+// Both should be grouped by the same aggregates; the limit of hs is not applied,
+// but the overflow entry of other is counted in the overflow entry of hs.
+// Percentiles are kept exact unless either entry keeps a sketch, in which case
+// the merged entry keeps a sketch, whose accuracy must be the same for both.
+func (hs *HTTPStats) Merge(other *HTTPStats) error {
+	aggregates := hs.Aggregates()
+
+	for _, s := range other.stats {
+		key := s.key(aggregates)
+		if other.isOverflow(s) {
+			key = overflowKey
+		}
+
+		idx := hs.hints.loadOrStore(key)
+		if idx >= len(hs.stats) {
+			hs.stats = append(hs.stats, s.emptyCopy())
+		}
+
+		err := hs.stats[idx].merge(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	hs.overflowCount += other.overflowCount
+
+	return nil
+}
+
+// emptyCopy returns an entry without requests with the keys of s.
+func (s *httpStat) emptyCopy() *httpStat {
+	c := &httpStat{
+		Uri:              s.Uri,
+		Method:           s.Method,
+		OperationID:      s.OperationID,
+		Dimensions:       s.Dimensions,
+		ResponseTime:     newResponseTime(s.ResponseTime.usePercentile, 0),
+		RequestBodySize:  newBodySize(s.RequestBodySize.usePercentile, 0),
+		ResponseBodySize: newBodySize(s.ResponseBodySize.usePercentile, 0),
+	}
+
+	if s.Apdex != nil {
+		c.Apdex = newApdex(s.Apdex.Threshold)
+	}
+
+	return c
+}
+
+func (s *httpStat) merge(other *httpStat) error {
+	s.Cnt += other.Cnt
+	s.Status1xx += other.Status1xx
+	s.Status2xx += other.Status2xx
+	s.Status3xx += other.Status3xx
+	s.Status4xx += other.Status4xx
+	s.Status5xx += other.Status5xx
+	s.StatusInvalid += other.StatusInvalid
+
+	for status, cnt := range other.Statuses {
+		if s.Statuses == nil {
+			s.Statuses = make(map[int]int)
+		}
+		s.Statuses[status] += cnt
+	}
+
+	if other.FirstTime != 0 && (s.FirstTime == 0 || other.FirstTime < s.FirstTime) {
+		s.FirstTime = other.FirstTime
+	}
+	if other.LastTime > s.LastTime {
+		s.LastTime = other.LastTime
+	}
+
+	if other.Apdex != nil {
+		if s.Apdex == nil {
+			s.Apdex = newApdex(other.Apdex.Threshold)
+		}
+
+		err := s.Apdex.merge(other.Apdex)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.ResponseTime.merge(other.ResponseTime)
+	if err != nil {
+		return err
+	}
+
+	err = s.RequestBodySize.merge(other.RequestBodySize)
+	if err != nil {
+		return err
+	}
+
+	return s.ResponseBodySize.merge(other.ResponseBodySize)
+}
+
+func (res *responseTime) merge(other *responseTime) error {
+	if other.welford.N > 0 {
+		res.Max, res.Min = mergeMinMax(res.Max, res.Min, res.welford.N, other.Max, other.Min)
+	}
+	res.Sum += other.Sum
+	res.welford.merge(other.welford)
+	res.usePercentile = res.usePercentile || other.usePercentile
+
+	return mergeSamples(&res.Percentiles, &res.sorted, &res.Sketch, other.Percentiles, other.Sketch)
+}
+
+func (body *bodySize) merge(other *bodySize) error {
+	if other.welford.N > 0 {
+		body.Max, body.Min = mergeMinMax(body.Max, body.Min, body.welford.N, other.Max, other.Min)
+	}
+	body.Sum += other.Sum
+	body.welford.merge(other.welford)
+	body.usePercentile = body.usePercentile || other.usePercentile
+
+	return mergeSamples(&body.Percentiles, &body.sorted, &body.Sketch, other.Percentiles, other.Sketch)
+}
+
+// mergeMinMax returns the max and min of the values of two summaries,
+// the first of which has n values.
+func mergeMinMax(max, min float64, n int, otherMax, otherMin float64) (float64, float64) {
+	if n == 0 {
+		return otherMax, otherMin
+	}
+
+	if otherMax > max {
+		max = otherMax
+	}
+	if otherMin < min {
+		min = otherMin
+	}
+
+	return max, min
+}
+
+// mergeSamples adds the percentile data of the other summary to samples or
+// sketch. The exact samples are moved into a sketch when the other has one.
+func mergeSamples(samples *[]float64, sorted *bool, sketch **ddSketch, otherSamples []float64, otherSketch *ddSketch) error {
+	if *sketch == nil && otherSketch != nil {
+		*sketch = newDDSketch(otherSketch.RelativeAccuracy, otherSketch.MaxBins)
+		for _, v := range *samples {
+			(*sketch).Add(v)
+		}
+		*samples = make([]float64, 0)
+	}
+
+	if *sketch != nil {
+		for _, v := range otherSamples {
+			(*sketch).Add(v)
+		}
+
+		if otherSketch != nil {
+			return (*sketch).Merge(otherSketch)
+		}
+
+		return nil
+	}
+
+	*samples = append(*samples, otherSamples...)
+	*sorted = false
+
+	return nil
+}
+
+// merge combines the running statistics of two sets of samples
+// (Chan et al.'s parallel algorithm).
+func (w *welford) merge(other welford) {
+	if other.N == 0 {
+		return
+	}
+
+	if w.N == 0 {
+		*w = other
+		return
+	}
+
+	n := w.N + other.N
+	delta := other.Mean - w.Mean
+	w.Mean += delta * float64(other.N) / float64(n)
+	w.M2 += other.M2 + delta*delta*float64(w.N)*float64(other.N)/float64(n)
+	w.N = n
+}
+
+func (a *apdex) merge(other *apdex) error {
+	if a.Threshold != other.Threshold {
+		return fmt.Errorf("cannot merge apdex with threshold %v and %v", a.Threshold, other.Threshold)
+	}
+
+	a.Satisfied += other.Satisfied
+	a.Tolerating += other.Tolerating
+	a.Frustrated += other.Frustrated
+
+	return nil
+}
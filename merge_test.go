@@ -0,0 +1,101 @@
+package httpstats
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	a := NewHTTPStats(true, false, false, NewPrintOptions())
+	a.Set("/foo", "GET", 200, 0.1, 10, 0)
+	a.Set("/foo", "GET", 200, 0.2, 20, 0)
+	a.Set("/bar", "GET", 404, 0.5, 0, 0)
+
+	b := NewHTTPStats(true, false, false, NewPrintOptions())
+	b.Set("/foo", "GET", 500, 0.4, 30, 0)
+	b.Set("/baz", "POST", 201, 0.3, 0, 0)
+
+	all := NewHTTPStats(true, false, false, NewPrintOptions())
+	for _, r := range []struct {
+		uri     string
+		status  int
+		restime float64
+		size    float64
+	}{{"/foo", 200, 0.1, 10}, {"/foo", 200, 0.2, 20}, {"/foo", 500, 0.4, 30}} {
+		all.Set(r.uri, "GET", r.status, r.restime, r.size, 0)
+	}
+
+	assert.Nil(t, a.Merge(b))
+	assert.Equal(t, 3, a.CountUris())
+
+	foo := a.Stats()[0]
+	want := all.Stats()[0]
+	assert.Equal(t, 3, foo.Count())
+	assert.Equal(t, 2, foo.Status2xx)
+	assert.Equal(t, 1, foo.Status5xx)
+	assert.Equal(t, map[int]int{200: 2, 500: 1}, foo.Statuses)
+	assert.Equal(t, 0.4, foo.MaxResponseTime())
+	assert.Equal(t, 0.1, foo.MinResponseTime())
+	assert.Equal(t, want.P50ResponseTime(), foo.P50ResponseTime())
+	assert.True(t, math.Abs(want.StddevResponseTime()-foo.StddevResponseTime()) < 1e-12)
+	assert.True(t, math.Abs(want.StddevResponseBodySize()-foo.StddevResponseBodySize()) < 1e-12)
+
+	// merged stats keep counting new requests in the same entries
+	a.Set("/baz", "POST", 201, 0.1, 0, 0)
+	assert.Equal(t, 2, a.Stats()[2].Count())
+}
+
+func TestMergeOverflow(t *testing.T) {
+	a := NewHTTPStats(true, false, false, NewPrintOptions())
+	a.SetLimit(1, "OTHER")
+	a.Set("/foo", "GET", 200, 0.1, 0, 0)
+	a.Set("/bar", "GET", 200, 0.1, 0, 0)
+	a.Set("/baz", "GET", 200, 0.1, 0, 0)
+
+	b := NewHTTPStats(true, false, false, NewPrintOptions())
+	b.SetLimit(1, "OTHER")
+	b.Set("/bar", "GET", 200, 0.1, 0, 0)
+	b.Set("/qux", "GET", 200, 0.1, 0, 0)
+
+	merged := a.emptyCopy()
+	assert.Nil(t, merged.Merge(a))
+	assert.Nil(t, merged.Merge(b))
+	assert.Equal(t, 3, merged.CountUris())
+	assert.Equal(t, 3, merged.OverflowCount())
+
+	merged.Set("/quux", "GET", 200, 0.1, 0, 0)
+	assert.Equal(t, 3, merged.CountUris())
+
+	other := merged.Stats()[1]
+	assert.Equal(t, "OTHER", other.Uri)
+	assert.Equal(t, 4, other.Count())
+}
+
+func TestMergeLoadedDumps(t *testing.T) {
+	sketch := NewHTTPStats(true, false, false, NewPrintOptions())
+	assert.Nil(t, sketch.SetPercentileBackend(PercentileSketch, 0.01))
+	sketch.Set("/foo", "GET", 200, 1, 0, 0)
+
+	exact := NewHTTPStats(true, false, false, NewPrintOptions())
+	exact.Set("/foo", "GET", 200, 2, 0, 0)
+	exact.Set("/foo", "GET", 200, 3, 0, 0)
+
+	merged := NewHTTPStats(true, false, false, NewPrintOptions())
+	for _, hs := range []*HTTPStats{exact, sketch} {
+		var buf bytes.Buffer
+		assert.Nil(t, hs.DumpStats(&buf))
+
+		loaded := NewHTTPStats(true, false, false, NewPrintOptions())
+		assert.Nil(t, loaded.LoadStats(&buf))
+		assert.Nil(t, merged.Merge(loaded))
+	}
+
+	s := merged.Stats()[0]
+	assert.Equal(t, 3, s.Count())
+	assert.NotNil(t, s.ResponseTime.Sketch)
+	assert.Equal(t, uint64(3), s.ResponseTime.Sketch.Count)
+	assert.InEpsilon(t, 2, s.P50ResponseTime(), 0.01)
+}
@@ -0,0 +1,135 @@
+package httpstats
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	NormalizeUUID   = "uuid"
+	NormalizeDate   = "date"
+	NormalizeEmail  = "email"
+	NormalizeID     = "id"
+	NormalizeHex    = "hex"
+	NormalizeBase64 = "base64"
+	// NormalizeAll enables every normalizer
+	NormalizeAll = "all"
+)
+
+// uriNormalizer replaces a whole path segment matched by match with placeholder.
+type uriNormalizer struct {
+	name        string
+	placeholder string
+	match       func(segment string) bool
+}
+
+// uriNormalizers are tried in this order on every path segment and the first
+// match wins, so that e.g. a UUID is not taken for a hex hash.
+var uriNormalizers = []*uriNormalizer{
+	{
+		name:        NormalizeUUID,
+		placeholder: ":uuid",
+		match:       regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+	},
+	{
+		name:        NormalizeDate,
+		placeholder: ":date",
+		match:       regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?)?$`).MatchString,
+	},
+	{
+		name:        NormalizeEmail,
+		placeholder: ":email",
+		match:       regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`).MatchString,
+	},
+	{
+		name:        NormalizeID,
+		placeholder: ":id",
+		match:       regexp.MustCompile(`^\d+$`).MatchString,
+	},
+	{
+		name:        NormalizeHex,
+		placeholder: ":hex",
+		match:       regexp.MustCompile(`^(?:[0-9a-f]{16,}|[0-9A-F]{16,})$`).MatchString,
+	},
+	{
+		name:        NormalizeBase64,
+		placeholder: ":base64",
+		match:       isBase64Token,
+	},
+}
+
+var base64Re = regexp.MustCompile(`^[A-Za-z0-9+_-]+={0,2}$`)
+
+// isBase64Token reports whether segment looks like a standard or URL-safe
+// base64 token: at least 20 characters mixing upper case, lower case and
+// digits, so that long words and lower case slugs are kept.
+func isBase64Token(segment string) bool {
+	if len(segment) < 20 || !base64Re.MatchString(segment) {
+		return false
+	}
+
+	var upper, lower, digit bool
+	for _, r := range segment {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+
+	return upper && lower && digit
+}
+
+func lookupURINormalizers(names []string) ([]*uriNormalizer, error) {
+	enabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == NormalizeAll {
+			return uriNormalizers, nil
+		}
+		enabled[name] = true
+	}
+
+	normalizers := make([]*uriNormalizer, 0, len(names))
+	for _, n := range uriNormalizers {
+		if enabled[n.name] {
+			normalizers = append(normalizers, n)
+			delete(enabled, n.name)
+		}
+	}
+
+	for name := range enabled {
+		return nil, fmt.Errorf("unknown uri normalizer: %s", name)
+	}
+
+	return normalizers, nil
+}
+
+// normalizeURI replaces the path segments of uri matched by normalizers with
+// their placeholders, e.g. /users/123/posts becomes /users/:id/posts.
+// The query string, if any, is kept as is.
+func normalizeURI(uri string, normalizers []*uriNormalizer) string {
+	path, query := uri, ""
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		path, query = uri[:i], uri[i:]
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+
+		for _, n := range normalizers {
+			if n.match(segment) {
+				segments[i] = n.placeholder
+				break
+			}
+		}
+	}
+
+	return strings.Join(segments, "/") + query
+}
@@ -0,0 +1,63 @@
+package httpstats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeURI(t *testing.T) {
+	all, err := lookupURINormalizers([]string{NormalizeAll})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{uri: "/", want: "/"},
+		{uri: "/users/12345/posts", want: "/users/:id/posts"},
+		{uri: "/users/12345/posts/67890", want: "/users/:id/posts/:id"},
+		{uri: "/orders/3f2504e0-4f89-11d3-9a0c-0305e82c3301", want: "/orders/:uuid"},
+		{uri: "/orders/3F2504E0-4F89-11D3-9A0C-0305E82C3301/items", want: "/orders/:uuid/items"},
+		{uri: "/assets/app.js", want: "/assets/app.js"},
+		{uri: "/commits/da39a3ee5e6b4b0d3255bfef95601890afd80709", want: "/commits/:hex"},
+		{uri: "/reports/2018-10-14/daily", want: "/reports/:date/daily"},
+		{uri: "/logs/2018-10-14T05:58:05+09:00", want: "/logs/:date"},
+		{uri: "/users/jane.doe+test@example.com/settings", want: "/users/:email/settings"},
+		{uri: "/reset/eyJhbGciOiJIUzI1NiJ9xQ3Zk_sUe-9Lr0aZ", want: "/reset/:base64"},
+		{uri: "/blog/how-to-tune-nginx-2018-edition", want: "/blog/how-to-tune-nginx-2018-edition"},
+		{uri: "/api/v1/internationalization", want: "/api/v1/internationalization"},
+		{uri: "/deadbeef", want: "/deadbeef"},
+		{uri: "/users/42?page=xxx", want: "/users/:id?page=xxx"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, normalizeURI(tt.uri, all), tt.uri)
+	}
+}
+
+func TestURINormalizersSelection(t *testing.T) {
+	normalizers, err := lookupURINormalizers([]string{NormalizeID})
+	assert.Nil(t, err)
+	assert.Equal(t, "/orders/3f2504e0-4f89-11d3-9a0c-0305e82c3301/lines/:id",
+		normalizeURI("/orders/3f2504e0-4f89-11d3-9a0c-0305e82c3301/lines/2", normalizers))
+
+	_, err = lookupURINormalizers([]string{"id", "ssn"})
+	assert.NotNil(t, err)
+}
+
+func TestURINormalizersWithCapturingGroups(t *testing.T) {
+	stats := NewHTTPStats(false, false, false, NewPrintOptions())
+	assert.Nil(t, stats.SetURICapturingGroups([]string{`^/static/.+$`}))
+	assert.Nil(t, stats.SetURINormalizers([]string{NormalizeID, NormalizeUUID}))
+
+	stats.Set("/users/1", "GET", 200, 0.1, 0, 0)
+	stats.Set("/users/2", "GET", 200, 0.1, 0, 0)
+	stats.Set("/static/12345.png", "GET", 200, 0.1, 0, 0)
+
+	s := stats.Stats()
+	assert.Equal(t, 2, len(s))
+	assert.Equal(t, "/users/:id", s[0].Uri)
+	assert.Equal(t, 2, s[0].Cnt)
+	assert.Equal(t, `^/static/.+$`, s[1].Uri)
+}
@@ -0,0 +1,183 @@
+package httpstats
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultUnmatchedUri = "unmatched"
+
+type openAPIDocument struct {
+	Servers []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Paths map[string]*openAPIPathItem `yaml:"paths"`
+}
+
+type openAPIPathItem struct {
+	Parameters []*openAPIParameter `yaml:"parameters"`
+	Get        *openAPIOperation   `yaml:"get"`
+	Put        *openAPIOperation   `yaml:"put"`
+	Post       *openAPIOperation   `yaml:"post"`
+	Delete     *openAPIOperation   `yaml:"delete"`
+	Options    *openAPIOperation   `yaml:"options"`
+	Head       *openAPIOperation   `yaml:"head"`
+	Patch      *openAPIOperation   `yaml:"patch"`
+	Trace      *openAPIOperation   `yaml:"trace"`
+}
+
+func (item *openAPIPathItem) operations() map[string]*openAPIOperation {
+	return map[string]*openAPIOperation{
+		"GET":     item.Get,
+		"PUT":     item.Put,
+		"POST":    item.Post,
+		"DELETE":  item.Delete,
+		"OPTIONS": item.Options,
+		"HEAD":    item.Head,
+		"PATCH":   item.Patch,
+		"TRACE":   item.Trace,
+	}
+}
+
+type openAPIOperation struct {
+	OperationID string              `yaml:"operationId"`
+	Parameters  []*openAPIParameter `yaml:"parameters"`
+}
+
+type openAPIParameter struct {
+	Name   string `yaml:"name"`
+	In     string `yaml:"in"`
+	Schema struct {
+		Type string `yaml:"type"`
+	} `yaml:"schema"`
+}
+
+var openAPIPathParamRe = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// LoadOpenAPIFile reads an OpenAPI 3 document, see LoadOpenAPI.
+func LoadOpenAPIFile(path string) ([]*URIGroup, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return []*URIGroup{}, err
+	}
+	defer f.Close()
+
+	return LoadOpenAPI(f)
+}
+
+// LoadOpenAPI builds one URIGroup per operation (method and path template)
+// of an OpenAPI 3 document in YAML or JSON. Each group is displayed as its
+// path template, prefixed with the path of the first server url, and carries
+// the operationId of the operation.
+// Groups for paths with fewer templated segments come first, so that
+// /users/me takes precedence over /users/{id}.
+func LoadOpenAPI(r io.Reader) ([]*URIGroup, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return []*URIGroup{}, err
+	}
+
+	var doc openAPIDocument
+	err = yaml.Unmarshal(buf, &doc)
+	if err != nil {
+		return []*URIGroup{}, err
+	}
+
+	basePath := ""
+	if len(doc.Servers) > 0 {
+		u, err := url.Parse(doc.Servers[0].URL)
+		if err == nil {
+			basePath = strings.TrimSuffix(u.Path, "/")
+		}
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		pi := len(openAPIPathParamRe.FindAllString(paths[i], -1))
+		pj := len(openAPIPathParamRe.FindAllString(paths[j], -1))
+		if pi != pj {
+			return pi < pj
+		}
+
+		return paths[i] < paths[j]
+	})
+
+	methods := []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+	groups := make([]*URIGroup, 0)
+	for _, path := range paths {
+		item := doc.Paths[path]
+		if item == nil {
+			continue
+		}
+
+		operations := item.operations()
+		for _, method := range methods {
+			op := operations[method]
+			if op == nil {
+				continue
+			}
+
+			params := pathParameterTypes(item.Parameters, op.Parameters)
+			g, err := NewURIGroup(openAPIPathToRegexp(basePath+path, params), basePath+path)
+			if err != nil {
+				return []*URIGroup{}, fmt.Errorf("%s %s: %s", method, path, err)
+			}
+			g.method = method
+			g.operationID = op.OperationID
+
+			groups = append(groups, g)
+		}
+	}
+
+	return groups, nil
+}
+
+// pathParameterTypes returns the schema type of each path parameter;
+// operation parameters override path item parameters of the same name.
+func pathParameterTypes(itemParams, opParams []*openAPIParameter) map[string]string {
+	types := make(map[string]string)
+	for _, params := range [][]*openAPIParameter{itemParams, opParams} {
+		for _, p := range params {
+			if p != nil && p.In == "path" {
+				types[p.Name] = p.Schema.Type
+			}
+		}
+	}
+
+	return types
+}
+
+func openAPIPathToRegexp(path string, paramTypes map[string]string) string {
+	var re strings.Builder
+	re.WriteString("^")
+
+	last := 0
+	for _, loc := range openAPIPathParamRe.FindAllStringSubmatchIndex(path, -1) {
+		re.WriteString(regexp.QuoteMeta(path[last:loc[0]]))
+
+		switch paramTypes[path[loc[2]:loc[3]]] {
+		case "integer":
+			re.WriteString("-?[0-9]+")
+		default:
+			re.WriteString("[^/]+")
+		}
+
+		last = loc[1]
+	}
+	re.WriteString(regexp.QuoteMeta(path[last:]))
+	re.WriteString("$")
+
+	return re.String()
+}
@@ -0,0 +1,86 @@
+package httpstats
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testOpenAPI = `openapi: 3.0.0
+info:
+  title: test
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users/{userId}:
+    parameters:
+      - name: userId
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      operationId: getUser
+    delete:
+      operationId: deleteUser
+  /users/me:
+    get:
+      operationId: getMe
+  /users/{userId}/posts/{post-id}:
+    get:
+      operationId: getPost
+      parameters:
+        - name: post-id
+          in: path
+          schema:
+            type: string
+`
+
+func TestLoadOpenAPI(t *testing.T) {
+	groups, err := LoadOpenAPI(bytes.NewBufferString(testOpenAPI))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(groups))
+
+	assert.Equal(t, "/v1/users/me", groups[0].Template())
+	assert.Equal(t, "GET", groups[0].Method())
+	assert.Equal(t, "getMe", groups[0].OperationID())
+
+	assert.Equal(t, "/v1/users/{userId}", groups[1].Template())
+	assert.Equal(t, "GET", groups[1].Method())
+	assert.Equal(t, "/v1/users/{userId}", groups[2].Template())
+	assert.Equal(t, "DELETE", groups[2].Method())
+	assert.Equal(t, "/v1/users/{userId}/posts/{post-id}", groups[3].Template())
+
+	assert.True(t, groups[1].Match("/v1/users/42"))
+	assert.False(t, groups[1].Match("/v1/users/jane"))
+	assert.True(t, groups[3].Match("/v1/users/42/posts/hello-world"))
+}
+
+func TestHTTPStatsLoadOpenAPI(t *testing.T) {
+	stats := NewHTTPStats(false, false, false, NewPrintOptions())
+	err := stats.LoadOpenAPI(bytes.NewBufferString(testOpenAPI))
+	assert.Nil(t, err)
+
+	stats.Set("/v1/users/me", "GET", 200, 0.1, 0, 0)
+	stats.Set("/v1/users/1", "GET", 200, 0.1, 0, 0)
+	stats.Set("/v1/users/2", "GET", 200, 0.1, 0, 0)
+	stats.Set("/v1/users/2", "DELETE", 204, 0.1, 0, 0)
+	stats.Set("/v1/users/2", "POST", 405, 0.1, 0, 0)
+	stats.Set("/v1/unknown", "GET", 404, 0.1, 0, 0)
+
+	s := stats.Stats()
+	assert.Equal(t, 5, len(s))
+
+	assert.Equal(t, "getMe", s[0].OperationID)
+	assert.Equal(t, "/v1/users/{userId}", s[1].Uri)
+	assert.Equal(t, "getUser", s[1].OperationID)
+	assert.Equal(t, 2, s[1].Cnt)
+	assert.Equal(t, "deleteUser", s[2].OperationID)
+
+	assert.Equal(t, "POST", s[3].Method)
+	assert.Equal(t, DefaultUnmatchedUri, s[3].Uri)
+	assert.Equal(t, "", s[3].OperationID)
+	assert.Equal(t, DefaultUnmatchedUri, s[4].Uri)
+}
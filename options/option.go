@@ -10,56 +10,78 @@ import (
 
 const (
 	DefaultSortOption         = "max"
+	DefaultParserOption       = "ltsv"
 	DefaultApptimeLabelOption = "apptime"
 	DefaultReqtimeLabelOption = "reqtime"
 	DefaultStatusLabelOption  = "status"
 	DefaultSizeLabelOption    = "size"
+	DefaultReqSizeLabelOption = "reqsize"
+	DefaultUaLabelOption      = "ua"
 	DefaultMethodLabelOption  = "method"
 	DefaultUriLabelOption     = "uri"
 	DefaultTimeLabelOption    = "time"
 	DefaultLimitOption        = 5000
+	DefaultOverflowUriOption  = "OTHER"
+	DefaultPercentileBackend  = "exact"
+	DefaultSketchAccuracy     = 0.01
 )
 
-func splitCSV(val string) []string {
-	strs := strings.Split(val, ",")
-	if len(strs) == 1 && strs[0] == "" {
-		return []string{}
-	}
-
-	trimedStrs := make([]string, 0, len(strs))
-
-	for _, s := range strs {
-		trimedStrs = append(trimedStrs, strings.Trim(s, " "))
+// SplitCSV splits a comma separated list, trimming the spaces around
+// the values and dropping the empty ones.
+func SplitCSV(csv string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(csv, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
 	}
 
-	return trimedStrs
+	return values
 }
 
 type Options struct {
-	File              string   `yaml:"file"`
-	Sort              string   `yaml:"sort"`
-	Reverse           bool     `yaml:"reverse"`
-	QueryString       bool     `yaml:"query_string"`
-	Tsv               bool     `yaml:"tsv"`
-	NoHeaders         bool     `yaml:no_headers`
-	ApptimeLabel      string   `yaml:"apptime_label"`
-	ReqtimeLabel      string   `yaml:"reqtime_label"`
-	StatusLabel       string   `yaml:"status_label"`
-	SizeLabel         string   `yaml:"size_label"`
-	MethodLabel       string   `yaml:"method_label"`
-	UriLabel          string   `yaml:"uri_label"`
-	TimeLabel         string   `yaml:"time_label"`
-	Limit             int      `yaml:"limit"`
-	Includes          []string `yaml:"includes"`
-	Excludes          []string `yaml:"excludes"`
-	IncludeStatuses   []string `yaml:"include_statuses"`
-	ExcludeStatuses   []string `yaml:"exclude_statuses"`
-	Aggregates        []string `yaml:"aggregates"`
-	StartTime         string   `yaml:"start_time"`
-	EndTime           string   `yaml:"end_time"`
-	StartTimeDuration string   `yaml:"start_time_duration"`
-	EndTimeDuration   string   `yaml:"end_time_duration"`
-	Location          string   `yaml:location`
+	File              string             `yaml:"file"`
+	Sort              string             `yaml:"sort"`
+	Reverse           bool               `yaml:"reverse"`
+	QueryString       bool               `yaml:"query_string"`
+	Tsv               bool               `yaml:"tsv"`
+	Format            string             `yaml:"format"`
+	NoHeaders         bool               `yaml:"no_headers"`
+	Parser            string             `yaml:"parser"`
+	Pattern           string             `yaml:"pattern"`
+	ApptimeLabel      string             `yaml:"apptime_label"`
+	ReqtimeLabel      string             `yaml:"reqtime_label"`
+	StatusLabel       string             `yaml:"status_label"`
+	SizeLabel         string             `yaml:"size_label"`
+	ReqSizeLabel      string             `yaml:"reqsize_label"`
+	MethodLabel       string             `yaml:"method_label"`
+	UriLabel          string             `yaml:"uri_label"`
+	TimeLabel         string             `yaml:"time_label"`
+	UaLabel           string             `yaml:"ua_label"`
+	Limit             int                `yaml:"limit"`
+	OverflowUri       string             `yaml:"overflow_uri"`
+	Includes          []string           `yaml:"includes"`
+	Excludes          []string           `yaml:"excludes"`
+	IncludeStatuses   []string           `yaml:"include_statuses"`
+	ExcludeStatuses   []string           `yaml:"exclude_statuses"`
+	Aggregates        []string           `yaml:"aggregates"`
+	StartTime         string             `yaml:"start_time"`
+	EndTime           string             `yaml:"end_time"`
+	StartTimeDuration string             `yaml:"start_time_duration"`
+	EndTimeDuration   string             `yaml:"end_time_duration"`
+	Location          string             `yaml:"location"`
+	UriGroups         []string           `yaml:"uri_groups"`
+	UriNormalizers    []string           `yaml:"uri_normalizers"`
+	OpenAPI           string             `yaml:"openapi"`
+	PercentileBackend string             `yaml:"percentile_backend"`
+	SketchAccuracy    float64            `yaml:"sketch_accuracy"`
+	Interval          string             `yaml:"interval"`
+	Dump              string             `yaml:"dump"`
+	ApdexThreshold    float64            `yaml:"apdex_threshold"`
+	ApdexThresholds   map[string]float64 `yaml:"apdex_thresholds"`
+	Percentiles       []float64          `yaml:"percentiles"`
+	Columns           []string           `yaml:"columns"`
 }
 
 type Option func(*Options)
@@ -104,6 +126,14 @@ func Tsv(b bool) Option {
 	}
 }
 
+func Format(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.Format = s
+		}
+	}
+}
+
 func NoHeaders(b bool) Option {
 	return func(opts *Options) {
 		if b {
@@ -112,6 +142,22 @@ func NoHeaders(b bool) Option {
 	}
 }
 
+func Parser(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.Parser = s
+		}
+	}
+}
+
+func Pattern(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.Pattern = s
+		}
+	}
+}
+
 func ApptimeLabel(s string) Option {
 	return func(opts *Options) {
 		if s != "" {
@@ -144,6 +190,14 @@ func SizeLabel(s string) Option {
 	}
 }
 
+func ReqSizeLabel(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.ReqSizeLabel = s
+		}
+	}
+}
+
 func MethodLabel(s string) Option {
 	return func(opts *Options) {
 		if s != "" {
@@ -168,6 +222,14 @@ func TimeLabel(s string) Option {
 	}
 }
 
+func UaLabel(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.UaLabel = s
+		}
+	}
+}
+
 func Limit(i int) Option {
 	return func(opts *Options) {
 		if i > 0 {
@@ -176,6 +238,14 @@ func Limit(i int) Option {
 	}
 }
 
+func OverflowUri(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.OverflowUri = s
+		}
+	}
+}
+
 func Includes(values []string) Option {
 	return func(opts *Options) {
 		if len(values) > 0 {
@@ -186,7 +256,7 @@ func Includes(values []string) Option {
 
 func CSVIncludes(csv string) Option {
 	return func(opts *Options) {
-		i := splitCSV(csv)
+		i := SplitCSV(csv)
 		if len(i) > 0 {
 			opts.Includes = i
 		}
@@ -204,7 +274,7 @@ func Excludes(values []string) Option {
 
 func CSVExcludes(csv string) Option {
 	return func(opts *Options) {
-		e := splitCSV(csv)
+		e := SplitCSV(csv)
 		if len(e) > 0 {
 			opts.Excludes = e
 		}
@@ -222,7 +292,7 @@ func IncludeStatuses(values []string) Option {
 
 func CSVIncludeStatuses(csv string) Option {
 	return func(opts *Options) {
-		i := splitCSV(csv)
+		i := SplitCSV(csv)
 		if len(i) > 0 {
 			opts.IncludeStatuses = i
 		}
@@ -239,7 +309,7 @@ func ExcludeStatuses(values []string) Option {
 
 func CSVExcludeStatuses(csv string) Option {
 	return func(opts *Options) {
-		e := splitCSV(csv)
+		e := SplitCSV(csv)
 		if len(e) > 0 {
 			opts.ExcludeStatuses = e
 		}
@@ -256,7 +326,7 @@ func Aggregates(values []string) Option {
 
 func CSVAggregates(csv string) Option {
 	return func(opts *Options) {
-		a := splitCSV(csv)
+		a := SplitCSV(csv)
 		if len(a) > 0 {
 			opts.Aggregates = a
 		}
@@ -303,17 +373,119 @@ func Location(s string) Option {
 	}
 }
 
+func UriGroups(values []string) Option {
+	return func(opts *Options) {
+		if len(values) > 0 {
+			opts.UriGroups = values
+		}
+	}
+}
+
+func UriNormalizers(values []string) Option {
+	return func(opts *Options) {
+		if len(values) > 0 {
+			opts.UriNormalizers = values
+		}
+	}
+}
+
+func OpenAPI(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.OpenAPI = s
+		}
+	}
+}
+
+func PercentileBackend(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.PercentileBackend = s
+		}
+	}
+}
+
+func SketchAccuracy(f float64) Option {
+	return func(opts *Options) {
+		if f > 0 {
+			opts.SketchAccuracy = f
+		}
+	}
+}
+
+func Percentiles(values []float64) Option {
+	return func(opts *Options) {
+		if len(values) > 0 {
+			opts.Percentiles = values
+		}
+	}
+}
+
+func Columns(values []string) Option {
+	return func(opts *Options) {
+		if len(values) > 0 {
+			opts.Columns = values
+		}
+	}
+}
+
+func CSVColumns(csv string) Option {
+	return func(opts *Options) {
+		c := SplitCSV(csv)
+		if len(c) > 0 {
+			opts.Columns = c
+		}
+	}
+}
+
+func Interval(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.Interval = s
+		}
+	}
+}
+
+func ApdexThreshold(f float64) Option {
+	return func(opts *Options) {
+		if f > 0 {
+			opts.ApdexThreshold = f
+		}
+	}
+}
+
+func ApdexThresholds(m map[string]float64) Option {
+	return func(opts *Options) {
+		if len(m) > 0 {
+			opts.ApdexThresholds = m
+		}
+	}
+}
+
+func Dump(s string) Option {
+	return func(opts *Options) {
+		if s != "" {
+			opts.Dump = s
+		}
+	}
+}
+
 func NewOptions(opt ...Option) *Options {
 	options := &Options{
-		Sort:         DefaultSortOption,
-		ApptimeLabel: DefaultApptimeLabelOption,
-		ReqtimeLabel: DefaultReqtimeLabelOption,
-		StatusLabel:  DefaultStatusLabelOption,
-		SizeLabel:    DefaultSizeLabelOption,
-		MethodLabel:  DefaultMethodLabelOption,
-		UriLabel:     DefaultUriLabelOption,
-		TimeLabel:    DefaultTimeLabelOption,
-		Limit:        DefaultLimitOption,
+		Sort:              DefaultSortOption,
+		Parser:            DefaultParserOption,
+		ApptimeLabel:      DefaultApptimeLabelOption,
+		ReqtimeLabel:      DefaultReqtimeLabelOption,
+		StatusLabel:       DefaultStatusLabelOption,
+		SizeLabel:         DefaultSizeLabelOption,
+		ReqSizeLabel:      DefaultReqSizeLabelOption,
+		MethodLabel:       DefaultMethodLabelOption,
+		UriLabel:          DefaultUriLabelOption,
+		TimeLabel:         DefaultTimeLabelOption,
+		Limit:             DefaultLimitOption,
+		OverflowUri:       DefaultOverflowUriOption,
+		PercentileBackend: DefaultPercentileBackend,
+		SketchAccuracy:    DefaultSketchAccuracy,
 	}
 
 	for _, o := range opt {
@@ -0,0 +1,7 @@
+package parsers
+
+import "errors"
+
+var (
+	SkipReadLineErr = errors.New("Skip read line")
+)
@@ -0,0 +1,153 @@
+package parsers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type JSONParser struct {
+	reader      *bufio.Reader
+	keys        *JSONKeys
+	strictMode  bool
+	queryString bool
+	fields      []string
+}
+
+// JSONKeys maps HTTPStat fields to keys of a JSON log line.
+// Nested objects are addressed with dotted paths (e.g. "request.uri").
+type JSONKeys struct {
+	Uri     string
+	Apptime string
+	Reqtime string
+	Size    string
+	ReqSize string
+	Status  string
+	Method  string
+	Time    string
+}
+
+func NewJSONKeys(uri, apptime, reqtime, size, status, method, time string) *JSONKeys {
+	return &JSONKeys{
+		Uri:     uri,
+		Apptime: apptime,
+		Reqtime: reqtime,
+		Size:    size,
+		Status:  status,
+		Method:  method,
+		Time:    time,
+	}
+}
+
+// SetReqSizeKey sets the key of the request body size, which is not collected when empty.
+func (k *JSONKeys) SetReqSizeKey(key string) {
+	k.ReqSize = key
+}
+
+func NewJSONParser(r io.Reader, keys *JSONKeys, query bool) *JSONParser {
+	return &JSONParser{
+		reader:      bufio.NewReader(r),
+		keys:        keys,
+		queryString: query,
+	}
+}
+
+func (j *JSONParser) Parse() (*HTTPStat, error) {
+	line, err := readLine(j.reader)
+	if err != nil {
+		return &HTTPStat{}, err
+	}
+
+	var parsedValue map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	err = decoder.Decode(&parsedValue)
+	if err != nil {
+		return &HTTPStat{}, errSkipReadLine(j.strictMode, err)
+	}
+
+	uri, err := normalizeURI(lookupJSONValue(parsedValue, j.keys.Uri), j.queryString)
+	if err != nil {
+		return &HTTPStat{}, errSkipReadLine(j.strictMode, err)
+	}
+
+	resTime, err := stringToFloat64(lookupJSONValue(parsedValue, j.keys.Apptime))
+	if err != nil {
+		var reqTime float64
+		reqTime, err = stringToFloat64(lookupJSONValue(parsedValue, j.keys.Reqtime))
+		if err != nil {
+			return &HTTPStat{}, errSkipReadLine(j.strictMode, err)
+		}
+
+		resTime = reqTime
+	}
+
+	bodySize, err := stringToFloat64(lookupJSONValue(parsedValue, j.keys.Size))
+	if err != nil {
+		return &HTTPStat{}, errSkipReadLine(j.strictMode, err)
+	}
+
+	reqBodySize, err := stringToOptionalFloat64(lookupJSONValue(parsedValue, j.keys.ReqSize))
+	if err != nil {
+		return &HTTPStat{}, errSkipReadLine(j.strictMode, err)
+	}
+
+	status := stringToStatus(lookupJSONValue(parsedValue, j.keys.Status))
+
+	method := lookupJSONValue(parsedValue, j.keys.Method)
+	timestr := lookupJSONValue(parsedValue, j.keys.Time)
+
+	stat := NewHTTPStat(uri, method, timestr, resTime, bodySize, status)
+	stat.SetRequestBodySize(reqBodySize)
+	stat.Fields = extractFields(j.fields, func(key string) string {
+		return lookupJSONValue(parsedValue, key)
+	})
+
+	return stat, nil
+}
+
+// SetFields sets the extra log fields copied into HTTPStat.Fields.
+func (j *JSONParser) SetFields(keys []string) {
+	j.fields = keys
+}
+
+// lookupJSONValue resolves a dotted key path and returns its value as a string.
+// A key that literally contains dots takes precedence over a nested lookup.
+func lookupJSONValue(values map[string]interface{}, key string) string {
+	if v, ok := values[key]; ok {
+		return jsonValueToString(v)
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] != '.' {
+			continue
+		}
+
+		nested, ok := values[key[:i]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if v := lookupJSONValue(nested, key[i+1:]); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func jsonValueToString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(val)
+	case json.Number:
+		return val.String()
+	default:
+		return fmt.Sprint(val)
+	}
+}
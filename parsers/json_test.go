@@ -0,0 +1,63 @@
+package parsers
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONParser(t *testing.T) {
+	data := bytes.NewBufferString(`{"time":"2018-10-14T05:58:05+09:00","method":"POST","uri":"/foo/bar?token=xxx&uuid=1234","status":200,"size":"12","apptime":0.057}
+
+{"time":"2018-10-14T05:58:06+09:00","request":{"method":"GET","uri":"/baz"},"status":"404","size":0,"apptime":"-","reqtime":"0.010"}
+{"time":"2018-10-14T05:58:07+09:00","method":"GET","uri":"/broken","status":"-","size":0,"apptime":0.1}
+not a json line`)
+
+	keys := NewJSONKeys("uri", "apptime", "reqtime", "size", "status", "method", "time")
+	parser := NewJSONParser(data, keys, false)
+
+	stat, err := parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, "/foo/bar", stat.Uri)
+	assert.Equal(t, "POST", stat.Method)
+	assert.Equal(t, "2018-10-14T05:58:05+09:00", stat.Time)
+	assert.Equal(t, 0.057, stat.ResponseTime)
+	assert.Equal(t, float64(12), stat.BodySize)
+	assert.Equal(t, 200, stat.Status)
+
+	nestedKeys := NewJSONKeys("request.uri", "apptime", "reqtime", "size", "status", "request.method", "time")
+	parser.keys = nestedKeys
+
+	stat, err = parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, "/baz", stat.Uri)
+	assert.Equal(t, "GET", stat.Method)
+	assert.Equal(t, 0.010, stat.ResponseTime)
+	assert.Equal(t, 404, stat.Status)
+
+	parser.keys = keys
+
+	stat, err = parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, "/broken", stat.Uri)
+	assert.Equal(t, InvalidStatus, stat.Status)
+
+	_, err = parser.Parse()
+	assert.Equal(t, SkipReadLineErr, err)
+
+	_, err = parser.Parse()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestJSONParserQueryString(t *testing.T) {
+	data := bytes.NewBufferString(`{"method":"GET","uri":"/foo?b=1&a=2","status":200,"size":1,"apptime":0.1}`)
+
+	keys := NewJSONKeys("uri", "apptime", "reqtime", "size", "status", "method", "time")
+	parser := NewJSONParser(data, keys, true)
+
+	stat, err := parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, "/foo?a=xxx&b=xxx", stat.Uri)
+}
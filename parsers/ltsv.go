@@ -1,12 +1,9 @@
 package parsers
 
 import (
-	"fmt"
 	"io"
-	"net/url"
 
 	"github.com/najeira/ltsv"
-	"github.com/tkuchiki/gohttpstats"
 )
 
 type LTSVParser struct {
@@ -14,6 +11,7 @@ type LTSVParser struct {
 	label       *LTSVLabel
 	strictMode  bool
 	queryString bool
+	fields      []string
 }
 
 type LTSVLabel struct {
@@ -21,6 +19,7 @@ type LTSVLabel struct {
 	Apptime string
 	Reqtime string
 	Size    string
+	ReqSize string
 	Status  string
 	Method  string
 	Time    string
@@ -38,6 +37,11 @@ func NewLTSVLabel(uri, apptime, reqtime, size, status, method, time string) *LTS
 	}
 }
 
+// SetReqSizeLabel sets the label of the request body size, which is not collected when empty.
+func (l *LTSVLabel) SetReqSizeLabel(label string) {
+	l.ReqSize = label
+}
+
 func NewLTSVParser(r io.Reader, l *LTSVLabel, query bool) *LTSVParser {
 	return &LTSVParser{
 		reader:      ltsv.NewReader(r),
@@ -54,26 +58,15 @@ func (l *LTSVParser) Parse() (*HTTPStat, error) {
 		return &HTTPStat{}, err
 	}
 
-	u, err := url.Parse(parsedValue[l.label.Uri])
+	uri, err := normalizeURI(parsedValue[l.label.Uri], l.queryString)
 	if err != nil {
 		return &HTTPStat{}, errSkipReadLine(l.strictMode, err)
 	}
-	var uri string
-	if l.queryString {
-		v := url.Values{}
-		values := u.Query()
-		for q := range values {
-			v.Set(q, "xxx")
-		}
-		uri = fmt.Sprintf("%s?%s", u.Path, v.Encode())
-	} else {
-		uri = u.Path
-	}
 
-	resTime, err := httpstats.StringToFloat64(parsedValue[l.label.Apptime])
+	resTime, err := stringToFloat64(parsedValue[l.label.Apptime])
 	if err != nil {
 		var reqTime float64
-		reqTime, err = httpstats.StringToFloat64(parsedValue[l.label.Reqtime])
+		reqTime, err = stringToFloat64(parsedValue[l.label.Reqtime])
 		if err != nil {
 			return &HTTPStat{}, errSkipReadLine(l.strictMode, err)
 		}
@@ -81,18 +74,31 @@ func (l *LTSVParser) Parse() (*HTTPStat, error) {
 		resTime = reqTime
 	}
 
-	bodySize, err := httpstats.StringToFloat64(parsedValue[l.label.Size])
+	bodySize, err := stringToFloat64(parsedValue[l.label.Size])
 	if err != nil {
 		return &HTTPStat{}, errSkipReadLine(l.strictMode, err)
 	}
 
-	status, err := httpstats.StringToInt(parsedValue[l.label.Status])
+	reqBodySize, err := stringToOptionalFloat64(parsedValue[l.label.ReqSize])
 	if err != nil {
 		return &HTTPStat{}, errSkipReadLine(l.strictMode, err)
 	}
 
+	status := stringToStatus(parsedValue[l.label.Status])
+
 	method := parsedValue[l.label.Method]
 	timestr := parsedValue[l.label.Time]
 
-	return NewHTTPStat(uri, method, timestr, resTime, bodySize, status), nil
+	stat := NewHTTPStat(uri, method, timestr, resTime, bodySize, status)
+	stat.SetRequestBodySize(reqBodySize)
+	stat.Fields = extractFields(l.fields, func(key string) string {
+		return parsedValue[key]
+	})
+
+	return stat, nil
+}
+
+// SetFields sets the extra log fields copied into HTTPStat.Fields.
+func (l *LTSVParser) SetFields(keys []string) {
+	l.fields = keys
 }
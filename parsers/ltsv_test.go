@@ -0,0 +1,35 @@
+package parsers
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLTSVParser(t *testing.T) {
+	data := bytes.NewBufferString("time:2018-10-14T05:58:05+09:00\tmethod:POST\turi:/upload?id=1\tstatus:201\tsize:12\treqsize:2048\tapptime:0.057\n" +
+		"time:2018-10-14T05:58:06+09:00\tmethod:GET\turi:/foo\tstatus:200\tsize:512\treqsize:-\tapptime:-\treqtime:0.010\n" +
+		"time:2018-10-14T05:58:07+09:00\tmethod:GET\turi:/foo\tstatus:200\tsize:-\tapptime:0.1\n")
+
+	label := NewLTSVLabel("uri", "apptime", "reqtime", "size", "status", "method", "time")
+	label.SetReqSizeLabel("reqsize")
+	parser := NewLTSVParser(data, label, false)
+
+	stat, err := parser.Parse()
+	assert.Nil(t, err)
+	want := NewHTTPStat("/upload", "POST", "2018-10-14T05:58:05+09:00", 0.057, 12, 201)
+	want.SetRequestBodySize(2048)
+	assert.Equal(t, want, stat)
+
+	stat, err = parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, NewHTTPStat("/foo", "GET", "2018-10-14T05:58:06+09:00", 0.010, 512, 200), stat)
+
+	_, err = parser.Parse()
+	assert.Equal(t, SkipReadLineErr, err)
+
+	_, err = parser.Parse()
+	assert.Equal(t, io.EOF, err)
+}
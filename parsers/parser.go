@@ -1,18 +1,40 @@
 package parsers
 
-import "github.com/tkuchiki/gohttpstats"
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+)
 
+// Parser reads one access log line per call.
+// Parse returns io.EOF at the end of input and SkipReadLineErr for a line
+// that cannot be parsed, unless the parser runs in strict mode.
 type Parser interface {
-	Parse() *HTTPStat
+	Parse() (*HTTPStat, error)
 }
 
+var (
+	_ Parser = (*LTSVParser)(nil)
+	_ Parser = (*JSONParser)(nil)
+	_ Parser = (*RegexpParser)(nil)
+)
+
+// InvalidStatus is the status of a request whose status could not be parsed.
+const InvalidStatus = 0
+
+// HTTPStat is one parsed log line. BodySize is the size of the response body.
+// Fields holds the extra fields requested with SetFields.
 type HTTPStat struct {
-	Uri          string
-	Method       string
-	Time         string
-	ResponseTime float64
-	BodySize     float64
-	Status       int
+	Uri             string
+	Method          string
+	Time            string
+	ResponseTime    float64
+	BodySize        float64
+	RequestBodySize float64
+	Status          int
+	Fields          map[string]string
 }
 
 func NewHTTPStat(uri, method, time string, resTime, bodySize float64, status int) *HTTPStat {
@@ -26,10 +48,88 @@ func NewHTTPStat(uri, method, time string, resTime, bodySize float64, status int
 	}
 }
 
+// SetRequestBodySize sets the size of the request body.
+func (s *HTTPStat) SetRequestBodySize(size float64) {
+	s.RequestBodySize = size
+}
+
+// extractFields looks up each of keys, or returns nil when there are none.
+func extractFields(keys []string, lookup func(key string) string) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]string, len(keys))
+	for _, key := range keys {
+		fields[key] = lookup(key)
+	}
+
+	return fields
+}
+
 func errSkipReadLine(strictMode bool, err error) error {
 	if strictMode {
 		return err
 	}
 
-	return httpstats.SkipReadLineErr
+	return SkipReadLineErr
+}
+
+func stringToFloat64(val string) (float64, error) {
+	return strconv.ParseFloat(val, 64)
+}
+
+// stringToOptionalFloat64 parses an optional field,
+// which is 0 when missing or logged as "-".
+func stringToOptionalFloat64(val string) (float64, error) {
+	if val == "" || val == "-" {
+		return 0, nil
+	}
+
+	return stringToFloat64(val)
+}
+
+// stringToStatus parses a status code. An unparseable status, such as "-",
+// is InvalidStatus so that the request is still counted.
+func stringToStatus(val string) int {
+	status, err := strconv.Atoi(val)
+	if err != nil {
+		return InvalidStatus
+	}
+
+	return status
+}
+
+func normalizeURI(rawURI string, queryString bool) (string, error) {
+	u, err := url.Parse(rawURI)
+	if err != nil {
+		return "", err
+	}
+
+	if !queryString {
+		return u.Path, nil
+	}
+
+	v := url.Values{}
+	values := u.Query()
+	for q := range values {
+		v.Set(q, "xxx")
+	}
+
+	return fmt.Sprintf("%s?%s", u.Path, v.Encode()), nil
+}
+
+// readLine returns the next non-empty line, without the trailing newline.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	for {
+		line, err := r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
 }
@@ -0,0 +1,156 @@
+package parsers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+const (
+	ApacheCommonPattern   = `^(?P<host>\S+) \S+ (?P<user>\S+) \[(?P<time>[^\]]+)\] "(?P<method>[A-Z]+) (?P<uri>[^ "]+)(?: [^"]*)?" (?P<status>\d{3}) (?P<size>\d+|-)`
+	ApacheCombinedPattern = ApacheCommonPattern + ` "(?P<referer>[^"]*)" "(?P<user_agent>[^"]*)"`
+	NginxMainPattern      = ApacheCombinedPattern + ` "(?P<forwarded_for>[^"]*)"`
+)
+
+// RegexpPresets are the built-in patterns selectable by name.
+var RegexpPresets = map[string]string{
+	"common":     ApacheCommonPattern,
+	"combined":   ApacheCombinedPattern,
+	"nginx_main": NginxMainPattern,
+}
+
+// Capture group names recognized by RegexpParser.
+// Only "uri" is required; the values of missing groups are 0 or empty.
+const (
+	RegexpUriGroup     = "uri"
+	RegexpApptimeGroup = "apptime"
+	RegexpReqtimeGroup = "reqtime"
+	RegexpSizeGroup    = "size"
+	RegexpReqSizeGroup = "reqsize"
+	RegexpStatusGroup  = "status"
+	RegexpMethodGroup  = "method"
+	RegexpTimeGroup    = "time"
+
+	// RegexpUserAgentGroup is the group of the user agent in the presets.
+	RegexpUserAgentGroup = "user_agent"
+)
+
+type RegexpParser struct {
+	reader      *bufio.Reader
+	re          *regexp.Regexp
+	strictMode  bool
+	queryString bool
+	fields      []string
+}
+
+// RegexpUserAgentField returns RegexpUserAgentGroup when pattern, a preset
+// name or a regular expression, captures it, and "" otherwise.
+func RegexpUserAgentField(pattern string) string {
+	if preset, ok := RegexpPresets[pattern]; ok {
+		pattern = preset
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil || re.SubexpIndex(RegexpUserAgentGroup) < 0 {
+		return ""
+	}
+
+	return RegexpUserAgentGroup
+}
+
+// NewRegexpParser returns a parser for pattern, which is either the name of
+// one of RegexpPresets or a regular expression with named capture groups.
+func NewRegexpParser(r io.Reader, pattern string, query bool) (*RegexpParser, error) {
+	if preset, ok := RegexpPresets[pattern]; ok {
+		pattern = preset
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if re.SubexpIndex(RegexpUriGroup) < 0 {
+		return nil, fmt.Errorf("pattern has no (?P<%s>...) capture group", RegexpUriGroup)
+	}
+
+	return &RegexpParser{
+		reader:      bufio.NewReader(r),
+		re:          re,
+		queryString: query,
+	}, nil
+}
+
+func (rp *RegexpParser) Parse() (*HTTPStat, error) {
+	line, err := readLine(rp.reader)
+	if err != nil {
+		return &HTTPStat{}, err
+	}
+
+	matches := rp.re.FindSubmatch(line)
+	if matches == nil {
+		return &HTTPStat{}, errSkipReadLine(rp.strictMode, fmt.Errorf("line does not match pattern: %s", line))
+	}
+
+	uri, err := normalizeURI(rp.group(matches, RegexpUriGroup), rp.queryString)
+	if err != nil {
+		return &HTTPStat{}, errSkipReadLine(rp.strictMode, err)
+	}
+
+	var resTime float64
+	if rp.hasGroup(RegexpApptimeGroup) || rp.hasGroup(RegexpReqtimeGroup) {
+		resTime, err = stringToFloat64(rp.group(matches, RegexpApptimeGroup))
+		if err != nil {
+			var reqTime float64
+			reqTime, err = stringToFloat64(rp.group(matches, RegexpReqtimeGroup))
+			if err != nil {
+				return &HTTPStat{}, errSkipReadLine(rp.strictMode, err)
+			}
+
+			resTime = reqTime
+		}
+	}
+
+	// common log format writes "-" when no body was sent
+	bodySize, err := stringToOptionalFloat64(rp.group(matches, RegexpSizeGroup))
+	if err != nil {
+		return &HTTPStat{}, errSkipReadLine(rp.strictMode, err)
+	}
+
+	reqBodySize, err := stringToOptionalFloat64(rp.group(matches, RegexpReqSizeGroup))
+	if err != nil {
+		return &HTTPStat{}, errSkipReadLine(rp.strictMode, err)
+	}
+
+	status := stringToStatus(rp.group(matches, RegexpStatusGroup))
+
+	method := rp.group(matches, RegexpMethodGroup)
+	timestr := rp.group(matches, RegexpTimeGroup)
+
+	stat := NewHTTPStat(uri, method, timestr, resTime, bodySize, status)
+	stat.SetRequestBodySize(reqBodySize)
+	stat.Fields = extractFields(rp.fields, func(key string) string {
+		return rp.group(matches, key)
+	})
+
+	return stat, nil
+}
+
+// SetFields sets the extra log fields copied into HTTPStat.Fields.
+func (rp *RegexpParser) SetFields(keys []string) {
+	rp.fields = keys
+}
+
+func (rp *RegexpParser) hasGroup(name string) bool {
+	return rp.re.SubexpIndex(name) >= 0
+}
+
+func (rp *RegexpParser) group(matches [][]byte, name string) string {
+	i := rp.re.SubexpIndex(name)
+	if i < 0 || i >= len(matches) {
+		return ""
+	}
+
+	return string(matches[i])
+}
@@ -0,0 +1,123 @@
+package parsers
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexpParserPresets(t *testing.T) {
+	tests := []struct {
+		preset string
+		line   string
+		uri    string
+		method string
+		status int
+		size   float64
+	}{
+		{
+			preset: "common",
+			line:   `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?v=1 HTTP/1.0" 200 2326`,
+			uri:    "/apache_pb.gif",
+			method: "GET",
+			status: 200,
+			size:   2326,
+		},
+		{
+			preset: "combined",
+			line:   `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "POST /foo/bar HTTP/1.1" 304 - "http://example.com/" "Mozilla/5.0 (X11; Linux x86_64)"`,
+			uri:    "/foo/bar",
+			method: "POST",
+			status: 304,
+			size:   0,
+		},
+		{
+			preset: "nginx_main",
+			line:   `10.0.0.1 - - [14/Oct/2018:05:58:05 +0900] "GET /users/1 HTTP/2.0" 404 153 "-" "curl/7.61.0" "192.168.0.1"`,
+			uri:    "/users/1",
+			method: "GET",
+			status: 404,
+			size:   153,
+		},
+	}
+
+	for _, tt := range tests {
+		parser, err := NewRegexpParser(bytes.NewBufferString(tt.line), tt.preset, false)
+		assert.Nil(t, err)
+
+		stat, err := parser.Parse()
+		assert.Nil(t, err, tt.preset)
+		assert.Equal(t, tt.uri, stat.Uri, tt.preset)
+		assert.Equal(t, tt.method, stat.Method, tt.preset)
+		assert.Equal(t, tt.status, stat.Status, tt.preset)
+		assert.Equal(t, tt.size, stat.BodySize, tt.preset)
+		assert.Equal(t, float64(0), stat.ResponseTime, tt.preset)
+
+		_, err = parser.Parse()
+		assert.Equal(t, io.EOF, err, tt.preset)
+	}
+}
+
+func TestRegexpParserCustomPattern(t *testing.T) {
+	data := bytes.NewBufferString(`GET /foo 200 10 0.123
+GET /bar 200 10 -
+garbage`)
+
+	parser, err := NewRegexpParser(data, `^(?P<method>\S+) (?P<uri>\S+) (?P<status>\d+) (?P<size>\d+) (?P<apptime>\S+)$`, false)
+	assert.Nil(t, err)
+
+	stat, err := parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, "/foo", stat.Uri)
+	assert.Equal(t, 0.123, stat.ResponseTime)
+
+	_, err = parser.Parse()
+	assert.Equal(t, SkipReadLineErr, err)
+
+	_, err = parser.Parse()
+	assert.Equal(t, SkipReadLineErr, err)
+
+	_, err = parser.Parse()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRegexpParserUriAndStatusOnly(t *testing.T) {
+	data := bytes.NewBufferString("/foo 200\n/bar 503\n")
+
+	parser, err := NewRegexpParser(data, `^(?P<uri>\S+) (?P<status>\d+)$`, false)
+	assert.Nil(t, err)
+	parser.strictMode = true
+
+	stat, err := parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTPStat{Uri: "/foo", Status: 200}, stat)
+
+	stat, err = parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTPStat{Uri: "/bar", Status: 503}, stat)
+}
+
+func TestNewRegexpParserRequiresUriGroup(t *testing.T) {
+	_, err := NewRegexpParser(bytes.NewBufferString(""), `^(?P<method>\S+)`, false)
+	assert.NotNil(t, err)
+}
+
+func TestRegexpParserFields(t *testing.T) {
+	line := `10.0.0.1 - - [14/Oct/2018:05:58:05 +0900] "GET /users/1 HTTP/2.0" 404 153 "-" "curl/7.61.0" "192.168.0.1"`
+	parser, err := NewRegexpParser(bytes.NewBufferString(line), "nginx_main", false)
+	assert.Nil(t, err)
+	parser.SetFields([]string{"host", "user_agent", "missing"})
+
+	stat, err := parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"host": "10.0.0.1", "user_agent": "curl/7.61.0", "missing": ""}, stat.Fields)
+}
+
+func TestRegexpUserAgentField(t *testing.T) {
+	assert.Equal(t, RegexpUserAgentGroup, RegexpUserAgentField("combined"))
+	assert.Equal(t, RegexpUserAgentGroup, RegexpUserAgentField("nginx_main"))
+	assert.Equal(t, "", RegexpUserAgentField("common"))
+	assert.Equal(t, RegexpUserAgentGroup, RegexpUserAgentField(`^(?P<uri>\S+) "(?P<user_agent>[^"]*)"`))
+}
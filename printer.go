@@ -1,6 +1,8 @@
 package httpstats
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -9,48 +11,18 @@ import (
 	"github.com/olekukonko/tablewriter"
 )
 
-var headers = map[string]string{
-	"count":      "Count",
-	"method":     "Method",
-	"uri":        "Uri",
-	"status_1xx": "Status_1xx",
-	"status_2xx": "Status_2xx",
-	"status_3xx": "Status_3xx",
-	"status_4xx": "Status_4xx",
-	"status_5xx": "Status_5xx",
-	"min":        "Min",
-	"max":        "Max",
-	"sum":        "Sum",
-	"avg":        "Avg",
-	"p1":         "P1",
-	"p50":        "P50",
-	"p99":        "P99",
-	"stddev":     "Stddev",
-	"min_body":   "Min(Body)",
-	"max_body":   "Max(Body)",
-	"sum_body":   "Sum(Body)",
-	"avg_body":   "Avg(Body)",
-}
-
-var defaultHeaders = []string{
-	"Count", "Method", "Uri", "1xx", "2xx", "3xx", "4xx", "5xx",
-	"Min", "Max", "Sum", "Avg",
-	"P1", "P50", "P99", "Stddev",
-	"Min(Body)", "Max(Body)", "Sum(Body)", "Avg(Body)",
-}
-
 type PrintOptions struct {
 	format    string
 	noHeaders bool
+	columns   []string
 	headers   []string
 	writer    io.Writer
 }
 
 func NewPrintOptions() *PrintOptions {
 	return &PrintOptions{
-		format:  "table",
-		headers: defaultHeaders,
-		writer:  os.Stdout,
+		format: "table",
+		writer: os.Stdout,
 	}
 }
 
@@ -58,6 +30,12 @@ func (p *PrintOptions) SetFormat(format string) {
 	p.format = format
 }
 
+func (p *PrintOptions) SetNoHeaders(b bool) {
+	p.noHeaders = b
+}
+
+// SetHeaders relabels the printed columns.
+// It is ignored unless there is exactly one label per column.
 func (p *PrintOptions) SetHeaders(headers []string) {
 	p.headers = headers
 }
@@ -66,49 +44,319 @@ func (p *PrintOptions) SetWriter(w io.Writer) {
 	p.writer = w
 }
 
-func (hs *HTTPStats) Print() {
-	switch hs.printOptions.format {
+// SetColumns selects the printed columns by key, e.g. "count", "uri", "p99",
+// or an aggregated dimension such as "status" or "host", so it must follow
+// SetAggregates. DefaultColumns are printed when none are set.
+func (hs *HTTPStats) SetColumns(keys []string) error {
+	for _, key := range keys {
+		if _, ok := hs.lookupColumn(key); !ok {
+			return fmt.Errorf("unknown column: %s", key)
+		}
+	}
+
+	hs.printOptions.columns = keys
+
+	return nil
+}
+
+func (hs *HTTPStats) Print() error {
+	return hs.printOptions.print(hs)
+}
+
+// printable is a table that PrintOptions can print:
+// rows for the text formats and values for the JSON formats.
+type printable interface {
+	headers() []string
+	rows() [][]string
+	values() []interface{}
+}
+
+func (p *PrintOptions) print(t printable) error {
+	switch p.format {
 	case "table":
-		hs.printTable()
+		p.printTable(t)
 	case "tsv":
-		hs.printTSV()
+		return p.printTSV(t)
+	case "csv":
+		return p.printCSV(t)
+	case "markdown":
+		return p.printMarkdown(t)
+	case "json":
+		return p.printJSON(t)
+	case "ndjson":
+		return p.printNDJSON(t)
 	}
+
+	return nil
 }
 
 func round(num float64) string {
 	return fmt.Sprintf("%.3f", num)
 }
 
-func (hs *HTTPStats) printTable() {
-	table := tablewriter.NewWriter(hs.printOptions.writer)
-	table.SetHeader(hs.printOptions.headers)
-	for _, s := range hs.stats {
-		data := []string{
-			s.StrCount(), s.Method, s.Uri,
-			s.StrStatus1xx(), s.StrStatus2xx(), s.StrStatus3xx(), s.StrStatus4xx(), s.StrStatus5xx(),
-			round(s.MinResponseTime()), round(s.MaxResponseTime()),
-			round(s.SumResponseTime()), round(s.AvgResponseTime()),
-			round(s.P1ResponseTime()), round(s.P50ResponseTime()), round(s.P99ResponseTime()),
-			round(s.StddevResponseTime()), round(s.MinResponseBodySize()), round(s.MaxResponseBodySize()), round(s.SumResponseBodySize()), round(s.AvgResponseBodySize()),
+func (hs *HTTPStats) columns() []*column {
+	keys := hs.printOptions.columns
+	if len(keys) == 0 {
+		keys = defaultColumns(hs.Aggregates(), hs.percentiles)
+	}
+
+	cols := make([]*column, 0, len(keys))
+	for _, key := range keys {
+		c, ok := hs.lookupColumn(key)
+		if !ok {
+			continue
 		}
-		table.Append(data)
+		cols = append(cols, c)
 	}
-	table.Render()
+
+	return cols
 }
 
-func (hs *HTTPStats) printTSV() {
-	if !hs.printOptions.noHeaders {
-		fmt.Println(strings.Join(hs.printOptions.headers, "\t"))
+func (hs *HTTPStats) headers() []string {
+	cols := hs.columns()
+	if len(hs.printOptions.headers) == len(cols) {
+		return hs.printOptions.headers
 	}
+
+	headers := make([]string, 0, len(cols))
+	for _, c := range cols {
+		headers = append(headers, c.header)
+	}
+
+	return headers
+}
+
+// formatRow formats s in the columns resolved by HTTPStats.columns.
+func formatRow(cols []*column, s *httpStat) []string {
+	data := make([]string, 0, len(cols))
+	for _, c := range cols {
+		data = append(data, c.format(s))
+	}
+
+	return data
+}
+
+func (hs *HTTPStats) rows() [][]string {
+	cols := hs.columns()
+	rows := make([][]string, 0, len(hs.stats))
+	for _, s := range hs.stats {
+		rows = append(rows, formatRow(cols, s))
+	}
+
+	return rows
+}
+
+func (hs *HTTPStats) values() []interface{} {
+	values := make([]interface{}, 0, len(hs.stats))
 	for _, s := range hs.stats {
-		data := []string{
-			s.StrCount(), s.Method, s.Uri,
-			s.StrStatus1xx(), s.StrStatus2xx(), s.StrStatus3xx(), s.StrStatus4xx(), s.StrStatus5xx(),
-			round(s.MinResponseTime()), round(s.MaxResponseTime()),
-			round(s.SumResponseTime()), round(s.AvgResponseTime()),
-			round(s.P1ResponseTime()), round(s.P50ResponseTime()), round(s.P99ResponseTime()),
-			round(s.StddevResponseTime()), round(s.MinResponseBodySize()), round(s.MaxResponseBodySize()), round(s.SumResponseBodySize()), round(s.AvgResponseBodySize()),
+		values = append(values, hs.jsonStat(s))
+	}
+
+	return values
+}
+
+func (p *PrintOptions) printTable(t printable) {
+	table := tablewriter.NewWriter(p.writer)
+	table.SetHeader(t.headers())
+	table.AppendBulk(t.rows())
+	table.Render()
+}
+
+func (p *PrintOptions) printTSV(t printable) error {
+	if !p.noHeaders {
+		_, err := fmt.Fprintln(p.writer, strings.Join(t.headers(), "\t"))
+		if err != nil {
+			return err
+		}
+	}
+	for _, row := range t.rows() {
+		_, err := fmt.Fprintln(p.writer, strings.Join(row, "\t"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printCSV prints RFC 4180 CSV, quoting fields that contain commas,
+// double quotes or line breaks.
+func (p *PrintOptions) printCSV(t printable) error {
+	w := csv.NewWriter(p.writer)
+	w.UseCRLF = true
+	if !p.noHeaders {
+		err := w.Write(t.headers())
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.WriteAll(t.rows())
+}
+
+// printMarkdown prints a GitHub Flavored Markdown table.
+// With noHeaders only the body rows are printed, to append to an existing table.
+func (p *PrintOptions) printMarkdown(t printable) error {
+	headers := t.headers()
+	if !p.noHeaders {
+		_, err := fmt.Fprintln(p.writer, markdownRow(headers))
+		if err != nil {
+			return err
+		}
+
+		separator := make([]string, len(headers))
+		for i := range separator {
+			separator[i] = "---"
+		}
+		_, err = fmt.Fprintln(p.writer, markdownRow(separator))
+		if err != nil {
+			return err
 		}
-		fmt.Println(strings.Join(data, "\t"))
 	}
+	for _, row := range t.rows() {
+		_, err := fmt.Fprintln(p.writer, markdownRow(row))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\n", " ", "\r", "")
+
+func markdownRow(cells []string) string {
+	escaped := make([]string, 0, len(cells))
+	for _, c := range cells {
+		escaped = append(escaped, markdownEscaper.Replace(c))
+	}
+
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
+
+// jsonStat is the JSON representation of an httpStat.
+// Its field names are part of the output format and must not change.
+type jsonStat struct {
+	Method           string            `json:"method"`
+	Uri              string            `json:"uri"`
+	OperationID      string            `json:"operation_id,omitempty"`
+	Dimensions       map[string]string `json:"dimensions,omitempty"`
+	Count            int               `json:"count"`
+	Status1xx        int               `json:"status_1xx"`
+	Status2xx        int               `json:"status_2xx"`
+	Status3xx        int               `json:"status_3xx"`
+	Status4xx        int               `json:"status_4xx"`
+	Status5xx        int               `json:"status_5xx"`
+	Statuses         map[int]int       `json:"statuses,omitempty"`
+	StatusInvalid    int               `json:"status_invalid"`
+	Throughput       float64           `json:"rps"`
+	ErrorRate4xx     float64           `json:"4xx_rate"`
+	ErrorRate5xx     float64           `json:"5xx_rate"`
+	Apdex            *jsonApdex        `json:"apdex,omitempty"`
+	ResponseTime     *jsonSummary      `json:"response_time"`
+	RequestBodySize  *jsonSummary      `json:"request_body_size"`
+	ResponseBodySize *jsonSummary      `json:"response_body_size"`
+}
+
+type jsonApdex struct {
+	Threshold  float64 `json:"threshold"`
+	Score      float64 `json:"score"`
+	Satisfied  int     `json:"satisfied"`
+	Tolerating int     `json:"tolerating"`
+	Frustrated int     `json:"frustrated"`
+}
+
+type jsonSummary struct {
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Sum         float64            `json:"sum"`
+	Avg         float64            `json:"avg"`
+	Percentiles map[string]float64 `json:"percentiles"`
+	Stddev      float64            `json:"stddev"`
+}
+
+func (hs *HTTPStats) jsonStat(s *httpStat) *jsonStat {
+	resTime := &jsonSummary{
+		Min:         s.MinResponseTime(),
+		Max:         s.MaxResponseTime(),
+		Sum:         s.SumResponseTime(),
+		Avg:         s.AvgResponseTime(),
+		Percentiles: make(map[string]float64, len(hs.percentiles)),
+		Stddev:      s.StddevResponseTime(),
+	}
+	reqBody := &jsonSummary{
+		Min:         s.MinRequestBodySize(),
+		Max:         s.MaxRequestBodySize(),
+		Sum:         s.SumRequestBodySize(),
+		Avg:         s.AvgRequestBodySize(),
+		Percentiles: make(map[string]float64, len(hs.percentiles)),
+		Stddev:      s.StddevRequestBodySize(),
+	}
+	resBody := &jsonSummary{
+		Min:         s.MinResponseBodySize(),
+		Max:         s.MaxResponseBodySize(),
+		Sum:         s.SumResponseBodySize(),
+		Avg:         s.AvgResponseBodySize(),
+		Percentiles: make(map[string]float64, len(hs.percentiles)),
+		Stddev:      s.StddevResponseBodySize(),
+	}
+
+	for _, p := range hs.percentiles {
+		key := PercentileKey(p)
+		resTime.Percentiles[key] = s.PercentileResponseTime(p)
+		reqBody.Percentiles[key] = s.PercentileRequestBodySize(p)
+		resBody.Percentiles[key] = s.PercentileResponseBodySize(p)
+	}
+
+	var apdex *jsonApdex
+	if s.Apdex != nil {
+		apdex = &jsonApdex{
+			Threshold:  s.Apdex.Threshold,
+			Score:      s.Apdex.Score(),
+			Satisfied:  s.Apdex.Satisfied,
+			Tolerating: s.Apdex.Tolerating,
+			Frustrated: s.Apdex.Frustrated,
+		}
+	}
+
+	return &jsonStat{
+		Method:           s.Method,
+		Uri:              s.Uri,
+		OperationID:      s.OperationID,
+		Dimensions:       s.Dimensions,
+		Count:            s.Count(),
+		Status1xx:        s.Status1xx,
+		Status2xx:        s.Status2xx,
+		Status3xx:        s.Status3xx,
+		Status4xx:        s.Status4xx,
+		Status5xx:        s.Status5xx,
+		Statuses:         s.Statuses,
+		StatusInvalid:    s.StatusInvalid,
+		Throughput:       s.Throughput(),
+		ErrorRate4xx:     s.ErrorRate4xx(),
+		ErrorRate5xx:     s.ErrorRate5xx(),
+		Apdex:            apdex,
+		ResponseTime:     resTime,
+		RequestBodySize:  reqBody,
+		ResponseBodySize: resBody,
+	}
+}
+
+func (p *PrintOptions) printJSON(t printable) error {
+	encoder := json.NewEncoder(p.writer)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(t.values())
+}
+
+func (p *PrintOptions) printNDJSON(t printable) error {
+	encoder := json.NewEncoder(p.writer)
+	for _, v := range t.values() {
+		err := encoder.Encode(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,205 @@
+package httpstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintTSVPercentiles(t *testing.T) {
+	outw := new(bytes.Buffer)
+	po := NewPrintOptions()
+	po.SetFormat("tsv")
+	po.SetWriter(outw)
+
+	stats := NewHTTPStats(true, false, false, po)
+	err := stats.SetPercentiles([]float64{95, 99.9})
+	assert.Nil(t, err)
+
+	for i := 1; i <= 1000; i++ {
+		stats.Set("/foo", "GET", 200, float64(i)/1000, 0, 0)
+	}
+	stats.Set("/bar", "GET", 200, 2, 0, 0)
+
+	stats.Sort("p95", true)
+	stats.Print()
+
+	lines := strings.Split(strings.TrimSpace(outw.String()), "\n")
+	headers := strings.Split(lines[0], "\t")
+	assert.Equal(t, []string{"Avg", "P95", "P99.9", "Stddev"}, headers[11:15])
+
+	row := strings.Split(lines[1], "\t")
+	assert.Equal(t, "/bar", row[2])
+
+	row = strings.Split(lines[2], "\t")
+	assert.Equal(t, []string{"0.950", "0.999"}, row[12:14])
+
+	assert.NotNil(t, stats.SetPercentiles([]float64{0}))
+	assert.NotNil(t, stats.SetPercentiles([]float64{100.1}))
+}
+
+func TestParsePercentileKey(t *testing.T) {
+	tests := []struct {
+		key string
+		p   float64
+		ok  bool
+	}{
+		{key: "p95", p: 95, ok: true},
+		{key: "P99.9", p: 99.9, ok: true},
+		{key: "p100", p: 100, ok: true},
+		{key: "p0", ok: false},
+		{key: "p", ok: false},
+		{key: "max", ok: false},
+	}
+
+	for _, tt := range tests {
+		p, ok := ParsePercentileKey(tt.key)
+		assert.Equal(t, tt.ok, ok, tt.key)
+		assert.Equal(t, tt.p, p, tt.key)
+	}
+
+	assert.Equal(t, "p99.9", PercentileKey(99.9))
+}
+
+func TestPrintJSON(t *testing.T) {
+	outw := new(bytes.Buffer)
+	po := NewPrintOptions()
+	po.SetFormat("ndjson")
+	po.SetWriter(outw)
+
+	stats := NewHTTPStats(true, false, false, po)
+	stats.Set("/foo", "GET", 200, 0.1, 10, 0)
+	stats.Set("/foo", "GET", 500, 0.3, 30, 0)
+	stats.Set("/bar", "POST", 404, 0.2, 0, 0)
+	stats.Print()
+
+	lines := strings.Split(strings.TrimSpace(outw.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+
+	var s map[string]interface{}
+	err := json.Unmarshal([]byte(lines[0]), &s)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "GET", s["method"])
+	assert.Equal(t, "/foo", s["uri"])
+	assert.Equal(t, float64(2), s["count"])
+	assert.Equal(t, float64(1), s["status_2xx"])
+	assert.Equal(t, float64(1), s["status_5xx"])
+
+	resTime := s["response_time"].(map[string]interface{})
+	assert.Equal(t, 0.3, resTime["max"])
+	assert.InDelta(t, 0.2, resTime["avg"], 1e-9)
+	assert.InDelta(t, 0.1, resTime["stddev"], 1e-9)
+	assert.Equal(t, map[string]interface{}{"p1": 0.1, "p50": 0.1, "p99": 0.3}, resTime["percentiles"])
+
+	for _, key := range []string{"request_body_size", "response_body_size"} {
+		_, ok := s[key].(map[string]interface{})
+		assert.True(t, ok, key)
+	}
+
+	outw.Reset()
+	po.SetFormat("json")
+	stats.Print()
+
+	var all []map[string]interface{}
+	err = json.Unmarshal(outw.Bytes(), &all)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(all))
+	assert.Equal(t, "/bar", all[1]["uri"])
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestPrintWriteError(t *testing.T) {
+	po := NewPrintOptions()
+	po.SetWriter(failingWriter{})
+	stats := NewHTTPStats(true, false, false, po)
+	stats.Set("/foo", "GET", 200, 0.1, 0, 0)
+
+	for _, format := range []string{"json", "ndjson", "tsv", "csv", "markdown"} {
+		po.SetFormat(format)
+		assert.NotNil(t, stats.Print(), format)
+	}
+}
+
+func TestPrintCSV(t *testing.T) {
+	outw := new(bytes.Buffer)
+	po := NewPrintOptions()
+	po.SetFormat("csv")
+	po.SetWriter(outw)
+
+	stats := NewHTTPStats(true, false, false, po)
+	stats.Set(`/search?q=a,b&"x"`, "GET", 200, 0.1, 10, 0)
+	stats.Print()
+
+	lines := strings.Split(outw.String(), "\r\n")
+	assert.True(t, strings.HasPrefix(lines[0], "Count,Method,Uri,1xx,"))
+	assert.True(t, strings.HasPrefix(lines[1], `1,GET,"/search?q=a,b&""x""",0,1,`))
+
+	outw.Reset()
+	po.SetNoHeaders(true)
+	stats.Print()
+	assert.True(t, strings.HasPrefix(outw.String(), "1,GET,"))
+}
+
+func TestPrintMarkdown(t *testing.T) {
+	outw := new(bytes.Buffer)
+	po := NewPrintOptions()
+	po.SetFormat("markdown")
+	po.SetWriter(outw)
+
+	stats := NewHTTPStats(true, false, false, po)
+	stats.Set("/a|b", "GET", 200, 0.1, 10, 0)
+	stats.Print()
+
+	lines := strings.Split(strings.TrimSpace(outw.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "| Count | Method | Uri | 1xx |"))
+	assert.True(t, strings.HasPrefix(lines[1], "| --- | --- | --- |"))
+	assert.True(t, strings.HasPrefix(lines[2], `| 1 | GET | /a\|b | 0 | 1 |`))
+	assert.Equal(t, strings.Count(lines[0], "|"), strings.Count(lines[2], "|")-1)
+
+	outw.Reset()
+	po.SetNoHeaders(true)
+	stats.Print()
+	assert.True(t, strings.HasPrefix(outw.String(), "| 1 | GET |"))
+}
+
+func TestPrintColumns(t *testing.T) {
+	outw := new(bytes.Buffer)
+	po := NewPrintOptions()
+	po.SetFormat("tsv")
+	po.SetWriter(outw)
+
+	stats := NewHTTPStats(true, false, false, po)
+	err := stats.SetColumns([]string{"count", "method", "uri", "p99", "avg"})
+	assert.Nil(t, err)
+
+	stats.Set("/foo", "GET", 200, 0.1, 10, 0)
+	stats.Set("/foo", "GET", 200, 0.3, 10, 0)
+	stats.Set("/bar", "POST", 200, 0.5, 10, 0)
+	stats.Sort("max", true)
+	stats.Print()
+
+	assert.Equal(t, "Count\tMethod\tUri\tP99\tAvg\n1\tPOST\t/bar\t0.500\t0.500\n2\tGET\t/foo\t0.300\t0.200\n", outw.String())
+
+	outw.Reset()
+	po.SetHeaders([]string{"A", "B"})
+	stats.Print()
+	assert.True(t, strings.HasPrefix(outw.String(), "Count\tMethod\tUri\tP99\tAvg\n"))
+
+	outw.Reset()
+	po.SetHeaders([]string{"N", "M", "U", "P", "A"})
+	stats.Print()
+	assert.True(t, strings.HasPrefix(outw.String(), "N\tM\tU\tP\tA\n"))
+
+	assert.NotNil(t, stats.SetColumns([]string{"count", "unknown"}))
+}
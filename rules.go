@@ -0,0 +1,314 @@
+package httpstats
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Rules are thresholds on the stats of the entries, such as service level
+// objectives, loaded from YAML:
+//
+//	rules:
+//	  - name: users api
+//	    uri: /users/:id
+//	    method: GET
+//	    checks:
+//	      - p99 < 0.5
+//	      - 5xx_rate < 0.01
+//	      - count > 100
+type Rules struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Rule checks the entries whose uri matches Uri, a uri group pattern
+// (see CompileURIGroup), and whose method is Method, combined; empty matches all.
+// A rule that matches no entry fails unless it is Optional.
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Uri      string   `yaml:"uri"`
+	Method   string   `yaml:"method"`
+	Checks   []string `yaml:"checks"`
+	Optional bool     `yaml:"optional"`
+
+	group  *URIGroup
+	checks []*ruleCheck
+}
+
+// ruleCheck is a check such as "p99 < 0.5": a numeric column, an operator and a threshold.
+type ruleCheck struct {
+	expr      string
+	metric    string
+	column    *column
+	operator  string
+	threshold float64
+}
+
+var ruleOperators = map[string]func(a, b float64) bool{
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+func LoadRulesFile(path string) (*Rules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadRules(f)
+}
+
+// LoadRules reads and compiles rules.
+func LoadRules(r io.Reader) (*Rules, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules Rules
+	err = yaml.Unmarshal(buf, &rules)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules.Rules {
+		err = rule.compile()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &rules, nil
+}
+
+func (r *Rule) compile() error {
+	if r.Uri != "" {
+		g, err := CompileURIGroup(r.Uri)
+		if err != nil {
+			return err
+		}
+		r.group = g
+	}
+
+	if r.Name == "" {
+		r.Name = strings.TrimSpace(r.Method + " " + r.Uri)
+	}
+
+	r.checks = make([]*ruleCheck, 0, len(r.Checks))
+	for _, expr := range r.Checks {
+		c, err := parseRuleCheck(expr)
+		if err != nil {
+			return fmt.Errorf("rule %s: %s", r.Name, err)
+		}
+		r.checks = append(r.checks, c)
+	}
+
+	return nil
+}
+
+func parseRuleCheck(expr string) (*ruleCheck, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("check must be \"<column> <operator> <number>\": %s", expr)
+	}
+
+	c, ok := lookupColumn(fields[0])
+	if !ok || c.value == nil {
+		return nil, fmt.Errorf("unknown numeric column: %s", fields[0])
+	}
+
+	if _, ok := ruleOperators[fields[1]]; !ok {
+		return nil, fmt.Errorf("unknown operator: %s", fields[1])
+	}
+
+	threshold, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold: %s", fields[2])
+	}
+
+	return &ruleCheck{
+		expr:      expr,
+		metric:    fields[0],
+		column:    c,
+		operator:  fields[1],
+		threshold: threshold,
+	}, nil
+}
+
+func (r *Rule) match(s *httpStat) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, s.Method) {
+		return false
+	}
+
+	return r.group == nil || r.group.Match(s.Uri)
+}
+
+// pattern returns the method and uri the rule matches, "*" for every entry.
+func (r *Rule) pattern() string {
+	p := strings.TrimSpace(r.Method + " " + r.Uri)
+	if p == "" {
+		return "*"
+	}
+
+	return p
+}
+
+// RuleResult is the result of a check of a rule. Entry is the pattern of
+// the entries the rule matched, and is empty when it matched none.
+type RuleResult struct {
+	Rule   string
+	Entry  string
+	Check  string
+	Value  float64
+	Passed bool
+}
+
+func (r *RuleResult) String() string {
+	result := "PASS"
+	if !r.Passed {
+		result = "FAIL"
+	}
+
+	if r.Entry == "" {
+		return fmt.Sprintf("%s [%s] no entry matches", result, r.Rule)
+	}
+
+	return fmt.Sprintf("%s [%s] %s: %s (%s)", result, r.Rule, r.Entry, r.Check,
+		strconv.FormatFloat(r.Value, 'f', -1, 64))
+}
+
+// CheckReport is the result of Check.
+type CheckReport struct {
+	Results []*RuleResult
+}
+
+// Check evaluates the rules against hs: the checks of a rule apply to the
+// summary of all the entries the rule matches.
+func (hs *HTTPStats) Check(rules *Rules) (*CheckReport, error) {
+	report := &CheckReport{}
+
+	for _, rule := range rules.Rules {
+		var summary *httpStat
+		for _, s := range hs.stats {
+			if !rule.match(s) {
+				continue
+			}
+
+			if summary == nil {
+				summary = s.emptyCopy()
+			}
+			err := summary.merge(s)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if summary == nil {
+			report.Results = append(report.Results, &RuleResult{
+				Rule:   rule.Name,
+				Passed: rule.Optional,
+			})
+			continue
+		}
+
+		for _, c := range rule.checks {
+			v := c.column.value(summary)
+			report.Results = append(report.Results, &RuleResult{
+				Rule:   rule.Name,
+				Entry:  rule.pattern(),
+				Check:  c.expr,
+				Value:  v,
+				Passed: ruleOperators[c.operator](v, c.threshold),
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// Violations returns the results that failed.
+func (r *CheckReport) Violations() []*RuleResult {
+	violations := make([]*RuleResult, 0)
+	for _, result := range r.Results {
+		if !result.Passed {
+			violations = append(violations, result)
+		}
+	}
+
+	return violations
+}
+
+func (r *CheckReport) Failed() bool {
+	return len(r.Violations()) > 0
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name         `xml:"testsuite"`
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the results as a JUnit XML test suite,
+// with a test case per rule and check.
+func (r *CheckReport) WriteJUnit(w io.Writer) error {
+	suite := &junitTestSuite{
+		Name:  "httpstats",
+		Tests: len(r.Results),
+	}
+
+	for _, result := range r.Results {
+		name := "no entry matches"
+		if result.Entry != "" {
+			name = result.Entry + ": " + result.Check
+		}
+
+		tc := &junitTestCase{
+			ClassName: result.Rule,
+			Name:      name,
+		}
+		if !result.Passed {
+			suite.Failures++
+			tc.Failure = &junitFailure{Message: result.String()}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(suite)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+
+	return err
+}
@@ -0,0 +1,79 @@
+package httpstats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRules(t *testing.T) {
+	rules, err := LoadRules(strings.NewReader(`rules:
+  - name: users
+    uri: /users/:id
+    method: GET
+    checks:
+      - p99 < 0.5
+      - 5xx_rate < 0.01
+      - count >= 2
+  - uri: ^/missing$
+  - uri: ^/optional$
+    optional: true
+`))
+	assert.Nil(t, err)
+
+	hs := NewHTTPStats(true, false, false, NewPrintOptions())
+	hs.Set("/users/1", "GET", 200, 0.1, 0, 0)
+	hs.Set("/users/1", "GET", 200, 0.2, 0, 0)
+	hs.Set("/users/2", "GET", 500, 0.7, 0, 0)
+	hs.Set("/users/1", "DELETE", 500, 0.7, 0, 0)
+
+	report, err := hs.Check(rules)
+	assert.Nil(t, err)
+	assert.True(t, report.Failed())
+	assert.Len(t, report.Results, 5)
+
+	var violations []string
+	for _, v := range report.Violations() {
+		violations = append(violations, v.String())
+	}
+	assert.Equal(t, []string{
+		"FAIL [users] GET /users/:id: p99 < 0.5 (0.7)",
+		"FAIL [users] GET /users/:id: 5xx_rate < 0.01 (0.3333333333333333)",
+		"FAIL [^/missing$] no entry matches",
+	}, violations)
+
+	var buf bytes.Buffer
+	assert.Nil(t, report.WriteJUnit(&buf))
+	assert.Contains(t, buf.String(), `<testsuite name="httpstats" tests="5" failures="3">`)
+	assert.Contains(t, buf.String(), `<testcase classname="users" name="GET /users/:id: count &gt;= 2"></testcase>`)
+}
+
+func TestCheckRulesCombinesEntries(t *testing.T) {
+	rules, err := LoadRules(strings.NewReader(`rules:
+  - uri: /users/:id
+    checks:
+      - count > 2
+`))
+	assert.Nil(t, err)
+
+	hs := NewHTTPStats(true, false, false, NewPrintOptions())
+	hs.Set("/users/1", "GET", 200, 0.1, 0, 0)
+	hs.Set("/users/1", "GET", 200, 0.1, 0, 0)
+	hs.Set("/users/2", "GET", 200, 0.1, 0, 0)
+	hs.Set("/users/2", "GET", 200, 0.1, 0, 0)
+
+	report, err := hs.Check(rules)
+	assert.Nil(t, err)
+	assert.False(t, report.Failed())
+	assert.Len(t, report.Results, 1)
+	assert.Equal(t, "PASS [/users/:id] /users/:id: count > 2 (4)", report.Results[0].String())
+}
+
+func TestLoadRulesErrors(t *testing.T) {
+	for _, check := range []string{"p99 <", "unknown < 1", "uri < 1", "p99 ~ 1", "p99 < x"} {
+		_, err := LoadRules(strings.NewReader("rules:\n  - checks: [\"" + check + "\"]\n"))
+		assert.NotNil(t, err, check)
+	}
+}
@@ -0,0 +1,176 @@
+package httpstats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/tkuchiki/gohttpstats/options"
+)
+
+const (
+	PercentileExact  = "exact"
+	PercentileSketch = "sketch"
+
+	DefaultSketchAccuracy = stats_options.DefaultSketchAccuracy
+	DefaultSketchMaxBins  = 2048
+
+	// values below this are counted in the zero bin
+	sketchMinIndexableValue = 1e-9
+)
+
+// ddSketch is a DDSketch quantile sketch (Masson et al., VLDB 2019).
+// Every quantile it returns is within RelativeAccuracy of the exact value,
+// and it keeps at most MaxBins bins however many values are added.
+// When MaxBins is exceeded the lowest bins are collapsed, so only the
+// accuracy of the smallest quantiles degrades.
+type ddSketch struct {
+	RelativeAccuracy float64        `yaml:"relative_accuracy"`
+	MaxBins          int            `yaml:"max_bins"`
+	Count            uint64         `yaml:"count"`
+	ZeroCount        uint64         `yaml:"zero_count"`
+	Bins             map[int]uint64 `yaml:"bins"`
+
+	// floor is the lowest index once bins were collapsed,
+	// whose bin counts the values of the lower indexes
+	floor     int
+	collapsed bool
+}
+
+func newDDSketch(relativeAccuracy float64, maxBins int) *ddSketch {
+	return &ddSketch{
+		RelativeAccuracy: relativeAccuracy,
+		MaxBins:          maxBins,
+		Bins:             make(map[int]uint64),
+	}
+}
+
+func (s *ddSketch) gamma() float64 {
+	return (1 + s.RelativeAccuracy) / (1 - s.RelativeAccuracy)
+}
+
+func (s *ddSketch) index(val float64) int {
+	return int(math.Ceil(math.Log(val) / math.Log(s.gamma())))
+}
+
+func (s *ddSketch) value(index int) float64 {
+	gamma := s.gamma()
+	return 2 * math.Pow(gamma, float64(index)) / (gamma + 1)
+}
+
+func (s *ddSketch) Add(val float64) {
+	s.Count++
+
+	if val < sketchMinIndexableValue {
+		s.ZeroCount++
+		return
+	}
+
+	if s.Bins == nil {
+		s.Bins = make(map[int]uint64)
+	}
+	s.Bins[s.binIndex(s.index(val))]++
+	if s.MaxBins > 0 && len(s.Bins) > s.MaxBins {
+		s.collapseLowest()
+	}
+}
+
+// binIndex returns the index of the bin that counts the values of index.
+func (s *ddSketch) binIndex(index int) int {
+	if s.collapsed && index < s.floor {
+		return s.floor
+	}
+
+	return index
+}
+
+// Quantile returns the value at quantile q (0 <= q <= 1) by the
+// nearest-rank method, like the exact percentiles.
+func (s *ddSketch) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	rank := math.Ceil(q*float64(s.Count)) - 1
+	cnt := float64(s.ZeroCount)
+	if cnt > rank {
+		return 0
+	}
+
+	indexes := s.sortedIndexes()
+	for _, i := range indexes {
+		cnt += float64(s.Bins[i])
+		if cnt > rank {
+			return s.value(i)
+		}
+	}
+
+	return s.value(indexes[len(indexes)-1])
+}
+
+// Merge adds the values of other to s.
+// Both sketches must have been created with the same relative accuracy.
+func (s *ddSketch) Merge(other *ddSketch) error {
+	if s.RelativeAccuracy != other.RelativeAccuracy {
+		return fmt.Errorf("cannot merge sketches with relative accuracy %v and %v", s.RelativeAccuracy, other.RelativeAccuracy)
+	}
+
+	if s.Bins == nil {
+		s.Bins = make(map[int]uint64)
+	}
+
+	s.Count += other.Count
+	s.ZeroCount += other.ZeroCount
+	for i, c := range other.Bins {
+		s.Bins[s.binIndex(i)] += c
+	}
+	s.collapse()
+
+	return nil
+}
+
+// collapse merges the lowest bins into one until at most MaxBins are left.
+func (s *ddSketch) collapse() {
+	if s.MaxBins <= 0 || len(s.Bins) <= s.MaxBins {
+		return
+	}
+
+	indexes := s.sortedIndexes()
+	over := len(indexes) - s.MaxBins
+	target := indexes[over]
+	for _, i := range indexes[:over] {
+		s.Bins[target] += s.Bins[i]
+		delete(s.Bins, i)
+	}
+	s.floor, s.collapsed = target, true
+}
+
+// collapseLowest merges the lowest bin into the next one, which is
+// enough when a single bin was added, without sorting the indexes.
+func (s *ddSketch) collapseLowest() {
+	lowest, next := 0, 0
+	n := 0
+	for i := range s.Bins {
+		switch {
+		case n == 0 || i < lowest:
+			lowest, next = i, lowest
+		case n == 1 || i < next:
+			next = i
+		}
+		n++
+	}
+
+	s.Bins[next] += s.Bins[lowest]
+	delete(s.Bins, lowest)
+	s.floor, s.collapsed = next, true
+}
+
+func (s *ddSketch) sortedIndexes() []int {
+	indexes := make([]int, 0, len(s.Bins))
+	for i := range s.Bins {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	return indexes
+}
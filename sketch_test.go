@@ -0,0 +1,91 @@
+package httpstats
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDDSketchQuantile(t *testing.T) {
+	sketch := newDDSketch(0.01, DefaultSketchMaxBins)
+	for i := 1; i <= 10000; i++ {
+		sketch.Add(float64(i) / 1000)
+	}
+
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.99} {
+		exact := q * 10
+		assert.InEpsilon(t, exact, sketch.Quantile(q), 0.011, "q=%v", q)
+	}
+	assert.Equal(t, uint64(10000), sketch.Count)
+}
+
+func TestDDSketchBoundedBins(t *testing.T) {
+	sketch := newDDSketch(0.01, 64)
+	for i := 0; i < 100000; i++ {
+		sketch.Add(math.Pow(1.001, float64(i)))
+	}
+
+	assert.True(t, len(sketch.Bins) <= 64)
+	assert.InEpsilon(t, math.Pow(1.001, 99000), sketch.Quantile(0.99), 0.011)
+}
+
+func TestDDSketchCollapsedLowValues(t *testing.T) {
+	sketch := newDDSketch(0.01, 4)
+	for i := 10; i > 0; i-- {
+		sketch.Add(float64(i))
+	}
+	sketch.Add(0.5)
+
+	var cnt uint64
+	for _, c := range sketch.Bins {
+		cnt += c
+	}
+	assert.Len(t, sketch.Bins, 4)
+	assert.Equal(t, sketch.Count, cnt)
+	assert.InEpsilon(t, 10, sketch.Quantile(1), 0.011)
+	assert.InEpsilon(t, 7, sketch.Quantile(0.1), 0.011)
+}
+
+func TestDDSketchMerge(t *testing.T) {
+	a := newDDSketch(0.01, DefaultSketchMaxBins)
+	b := newDDSketch(0.01, DefaultSketchMaxBins)
+	for i := 1; i <= 500; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i + 500))
+	}
+	b.Add(0)
+
+	err := a.Merge(b)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1001), a.Count)
+	assert.Equal(t, uint64(1), a.ZeroCount)
+	assert.InEpsilon(t, 500, a.Quantile(0.5), 0.011)
+
+	err = a.Merge(newDDSketch(0.02, DefaultSketchMaxBins))
+	assert.NotNil(t, err)
+}
+
+func TestSketchSurvivesDumpAndLoad(t *testing.T) {
+	stats := NewHTTPStats(true, false, false, NewPrintOptions())
+	err := stats.SetPercentileBackend(PercentileSketch, 0.01)
+	assert.Nil(t, err)
+
+	for i := 1; i <= 100; i++ {
+		stats.Set("/foo", "GET", 200, float64(i)/100, 0, 0)
+	}
+	assert.Empty(t, stats.Stats()[0].ResponseTime.Percentiles)
+
+	buf := new(bytes.Buffer)
+	err = stats.DumpStats(buf)
+	assert.Nil(t, err)
+
+	loaded := NewHTTPStats(true, false, false, NewPrintOptions())
+	err = loaded.LoadStats(buf)
+	assert.Nil(t, err)
+
+	s := loaded.Stats()[0]
+	assert.InEpsilon(t, stats.Stats()[0].P99ResponseTime(), s.P99ResponseTime(), 1e-9)
+	assert.InEpsilon(t, 0.5, s.P50ResponseTime(), 0.011)
+}
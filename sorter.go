@@ -35,7 +35,17 @@ const (
 	SortStddevResponseBodySize = "StddevResponseBodySize"
 )
 
+// Sort sorts by one of the Sort* constants, or by a column key such as
+// "max", "count" or "p99.9" (see HTTPStats.SetColumns).
+// It also sorts the samples of the exact percentiles.
 func (hs *HTTPStats) Sort(sortType string, reverse bool) {
+	hs.sortSamples()
+
+	if c, ok := hs.lookupColumn(sortType); ok {
+		hs.sortColumn(c, reverse)
+		return
+	}
+
 	switch sortType {
 	case SortCount:
 		hs.SortCount(reverse)
@@ -105,6 +115,18 @@ func (hs *HTTPStats) Sort(sortType string, reverse bool) {
 	}
 }
 
+func (hs *HTTPStats) sortColumn(c *column, reverse bool) {
+	if reverse {
+		sort.Slice(hs.stats, func(i, j int) bool {
+			return c.less(hs.stats[j], hs.stats[i])
+		})
+	} else {
+		sort.Slice(hs.stats, func(i, j int) bool {
+			return c.less(hs.stats[i], hs.stats[j])
+		})
+	}
+}
+
 func (hs *HTTPStats) SortCount(reverse bool) {
 	if reverse {
 		sort.Slice(hs.stats, func(i, j int) bool {
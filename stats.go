@@ -2,11 +2,16 @@ package httpstats
 
 import (
 	"fmt"
+	"io"
 	"math"
-	"regexp"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/tkuchiki/gohttpstats/options"
+	"github.com/tkuchiki/gohttpstats/parsers"
+	"github.com/tkuchiki/parsetime"
 )
 
 type hints struct {
@@ -21,6 +26,14 @@ func newHints() *hints {
 	}
 }
 
+func (h *hints) load(key string) (int, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	idx, ok := h.values[key]
+
+	return idx, ok
+}
+
 func (h *hints) loadOrStore(key string) int {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -41,39 +54,153 @@ type HTTPStats struct {
 	useResponseBodySizePercentile bool
 	printOptions                  *PrintOptions
 	filter                        *Filter
+	parseTime                     parsetime.ParseTime
 	options                       *stats_options.Options
-	uriCapturingGroups            []*regexp.Regexp
+	uriCapturingGroups            []*URIGroup
+	uriNormalizers                []*uriNormalizer
+	unmatchedUri                  string
+	sketchAccuracy                float64
+	percentiles                   []float64
+	limit                         int
+	overflowUri                   string
+	overflowCount                 int
+	aggregates                    []string
+	userAgentField                string
+	apdexThreshold                float64
 }
 
 func NewHTTPStats(useResTimePercentile, useRequestBodySizePercentile, useResponseBodySizePercentile bool, po *PrintOptions) *HTTPStats {
+	// the local time zone always loads
+	parseTime, _ := parsetime.NewParseTime("")
+
 	return &HTTPStats{
-		hints: newHints(),
-		stats: make([]*httpStat, 0),
+		hints:                         newHints(),
+		stats:                         make([]*httpStat, 0),
 		useResponseTimePercentile:     useResTimePercentile,
+		useRequestBodySizePercentile:  useRequestBodySizePercentile,
 		useResponseBodySizePercentile: useResponseBodySizePercentile,
 		printOptions:                  po,
+		percentiles:                   DefaultPercentiles,
+		parseTime:                     parseTime,
 	}
 }
 
+// emptyCopy returns an HTTPStats without entries
+// that shares the configuration of hs.
+func (hs *HTTPStats) emptyCopy() *HTTPStats {
+	c := *hs
+	c.hints = newHints()
+	c.stats = make([]*httpStat, 0)
+	c.overflowCount = 0
+
+	return &c
+}
+
 func (hs *HTTPStats) Set(uri, method string, status int, restime, resBodySize, reqBodySize float64) {
-	if len(hs.uriCapturingGroups) > 0 {
-		for _, re := range hs.uriCapturingGroups {
-			if ok := re.Match([]byte(uri)); ok {
-				pattern := re.String()
-				uri = pattern
-			}
-		}
+	hs.SetWithFields(uri, method, status, restime, resBodySize, reqBodySize, nil)
+}
+
+// SetWithFields is Set for a request with extra log fields,
+// which are looked up by the aggregates that name a log field.
+func (hs *HTTPStats) SetWithFields(uri, method string, status int, restime, resBodySize, reqBodySize float64, fields map[string]string) {
+	hs.set(uri, method, status, restime, resBodySize, reqBodySize, fields)
+}
+
+// SetHTTPStat sets a parsed log line. Unlike Set it also records its time,
+// parsed in the location of the filter, from which the throughput is computed.
+// A line whose time cannot be parsed is counted without it and InvalidTimeErr is returned.
+func (hs *HTTPStats) SetHTTPStat(stat *parsers.HTTPStat) error {
+	t, err := parseStatTime(hs.parseTime, stat.Time)
+	return hs.setHTTPStat(stat, t, err)
+}
+
+// setHTTPStat is SetHTTPStat for a line whose time is already parsed,
+// or failed to parse with timeErr.
+func (hs *HTTPStats) setHTTPStat(stat *parsers.HTTPStat, t time.Time, timeErr error) error {
+	s := hs.set(stat.Uri, stat.Method, stat.Status, stat.ResponseTime, stat.BodySize, stat.RequestBodySize, stat.Fields)
+	if timeErr != nil {
+		return InvalidTimeErr
+	}
+
+	if !t.IsZero() {
+		s.setTime(t)
 	}
 
-	key := fmt.Sprintf("%s_%s", method, uri)
+	return nil
+}
+
+// parseStatTime parses the time of a log line; the zero time if it has none.
+func parseStatTime(p parsetime.ParseTime, timestr string) (time.Time, error) {
+	if timestr == "" {
+		return time.Time{}, nil
+	}
+
+	return p.Parse(timestr)
+}
+
+// set counts the request and returns the entry it was counted in.
+func (hs *HTTPStats) set(uri, method string, status int, restime, resBodySize, reqBodySize float64, fields map[string]string) *httpStat {
+	uri, group := hs.groupURI(method, uri)
+	if !hs.aggregatesBy(DimensionUri) {
+		uri = ""
+		group = nil
+	}
+	if !hs.aggregatesBy(DimensionMethod) {
+		method = ""
+	}
+	dims := hs.dimensions(status, fields)
+
+	key := entryKey(hs.Aggregates(), uri, method, dims)
+
+	if hs.limit > 0 && hs.hints.len >= hs.limit {
+		if _, ok := hs.hints.load(key); !ok {
+			method = ""
+			uri = hs.overflowUri
+			group = nil
+			dims = nil
+			key = overflowKey
+			hs.overflowCount++
+		}
+	}
 
 	idx := hs.hints.loadOrStore(key)
 
 	if idx >= len(hs.stats) {
-		hs.stats = append(hs.stats, newHTTPStat(uri, method, hs.useResponseTimePercentile, hs.useRequestBodySizePercentile, hs.useResponseBodySizePercentile))
+		hs.stats = append(hs.stats, newHTTPStat(uri, method, hs.useResponseTimePercentile, hs.useRequestBodySizePercentile, hs.useResponseBodySizePercentile, hs.sketchAccuracy))
+		hs.stats[idx].Dimensions = dims
+		if t := hs.apdexThresholdOf(group); t > 0 {
+			hs.stats[idx].Apdex = newApdex(t)
+		}
+		if group != nil {
+			hs.stats[idx].OperationID = group.OperationID()
+		}
 	}
 
 	hs.stats[idx].Set(status, restime, resBodySize, reqBodySize)
+
+	return hs.stats[idx]
+}
+
+// groupURI returns the uri an entry is counted under and its group, if any.
+// The template of the first matching uri capturing group takes precedence;
+// otherwise the uri is replaced with the unmatched uri, if set, or else
+// the normalizers are applied.
+func (hs *HTTPStats) groupURI(method, uri string) (string, *URIGroup) {
+	for _, g := range hs.uriCapturingGroups {
+		if g.matchRequest(method, uri) {
+			return g.Template(), g
+		}
+	}
+
+	if hs.unmatchedUri != "" {
+		return hs.unmatchedUri, nil
+	}
+
+	if len(hs.uriNormalizers) > 0 {
+		uri = normalizeURI(uri, hs.uriNormalizers)
+	}
+
+	return uri, nil
 }
 
 func (hs *HTTPStats) Stats() []*httpStat {
@@ -84,12 +211,35 @@ func (hs *HTTPStats) CountUris() int {
 	return hs.hints.len
 }
 
+// SetLimit caps the number of distinct method and uri pairs at limit.
+// Requests for further pairs are counted in a single overflow entry whose
+// uri is overflowUri; that entry is not counted in limit. 0 disables the cap.
+func (hs *HTTPStats) SetLimit(limit int, overflowUri string) {
+	hs.limit = limit
+	hs.overflowUri = overflowUri
+}
+
+func (hs *HTTPStats) Limit() int {
+	return hs.limit
+}
+
+func (hs *HTTPStats) OverflowURI() string {
+	return hs.overflowUri
+}
+
+// OverflowCount returns the number of requests counted in the overflow entry.
+func (hs *HTTPStats) OverflowCount() int {
+	return hs.overflowCount
+}
+
 func (hs *HTTPStats) SetOptions(options *stats_options.Options) {
 	hs.options = options
 }
 
+// SetURICapturingGroups compiles groups with CompileURIGroup.
+// A uri is counted under the first group it matches, in the given order.
 func (hs *HTTPStats) SetURICapturingGroups(groups []string) error {
-	uriGroups, err := CompileUriGroups(groups)
+	uriGroups, err := compileURIGroups(groups)
 	if err != nil {
 		return err
 	}
@@ -99,12 +249,103 @@ func (hs *HTTPStats) SetURICapturingGroups(groups []string) error {
 	return nil
 }
 
+func (hs *HTTPStats) SetURIGroups(groups []*URIGroup) {
+	hs.uriCapturingGroups = groups
+}
+
+func (hs *HTTPStats) URIGroups() []*URIGroup {
+	return hs.uriCapturingGroups
+}
+
+// SetUnmatchedURI counts the requests that match no uri group under uri
+// instead of their own uri. An empty uri disables it.
+func (hs *HTTPStats) SetUnmatchedURI(uri string) {
+	hs.unmatchedUri = uri
+}
+
+// LoadOpenAPI groups the requests by the operations of an OpenAPI document
+// (see LoadOpenAPI) and counts the other requests as DefaultUnmatchedUri.
+func (hs *HTTPStats) LoadOpenAPI(r io.Reader) error {
+	groups, err := LoadOpenAPI(r)
+	if err != nil {
+		return err
+	}
+
+	hs.SetURIGroups(groups)
+	hs.SetUnmatchedURI(DefaultUnmatchedUri)
+
+	return nil
+}
+
+// SetURINormalizers enables the built-in normalizers by name (NormalizeID,
+// NormalizeUUID, ... or NormalizeAll), which replace path segments such as
+// numeric IDs with placeholders like ":id". They apply to uris that no uri
+// capturing group matches.
+func (hs *HTTPStats) SetURINormalizers(names []string) error {
+	normalizers, err := lookupURINormalizers(names)
+	if err != nil {
+		return err
+	}
+
+	hs.uriNormalizers = normalizers
+
+	return nil
+}
+
+// SetPercentiles sets the percentiles (0 < p <= 100) that are printed
+// as columns and accepted as sort keys, e.g. 95 for "p95".
+func (hs *HTTPStats) SetPercentiles(percentiles []float64) error {
+	for _, p := range percentiles {
+		if p <= 0 || p > 100 {
+			return fmt.Errorf("percentile must be greater than 0 and at most 100: %v", p)
+		}
+	}
+
+	hs.percentiles = percentiles
+
+	return nil
+}
+
+func (hs *HTTPStats) Percentiles() []float64 {
+	return hs.percentiles
+}
+
+// SetPercentileBackend selects how percentiles are computed for new entries.
+// PercentileExact keeps every sample; PercentileSketch keeps a DDSketch whose
+// memory is bounded and whose percentiles are within accuracy (e.g. 0.01 = 1%)
+// of the exact values.
+func (hs *HTTPStats) SetPercentileBackend(backend string, accuracy float64) error {
+	switch backend {
+	case PercentileExact, "":
+		hs.sketchAccuracy = 0
+	case PercentileSketch:
+		if accuracy <= 0 || accuracy >= 1 {
+			return fmt.Errorf("sketch accuracy must be between 0 and 1: %v", accuracy)
+		}
+		hs.sketchAccuracy = accuracy
+	default:
+		return fmt.Errorf("unknown percentile backend: %s", backend)
+	}
+
+	return nil
+}
+
 func (hs *HTTPStats) InitFilter(options *stats_options.Options) error {
 	hs.filter = NewFilter(options)
-	return hs.filter.Init()
+	err := hs.filter.Init()
+	if err != nil {
+		return err
+	}
+	hs.parseTime = hs.filter.parseTime
+
+	return nil
 }
 
 func (hs *HTTPStats) DoFilter(uri, status, timestr string) bool {
+	if hs.filter == nil {
+		return true
+	}
+
 	err := hs.filter.Do(uri, status, timestr)
 	if err == SkipReadLineErr || err != nil {
 		return false
@@ -113,45 +354,78 @@ func (hs *HTTPStats) DoFilter(uri, status, timestr string) bool {
 	return true
 }
 
+// doFilterTime is DoFilter for a line whose time is already parsed,
+// or failed to parse with timeErr.
+func (hs *HTTPStats) doFilterTime(uri, status string, t time.Time, timeErr error) bool {
+	if hs.filter == nil {
+		return true
+	}
+
+	return hs.filter.doTime(uri, status, t, timeErr) == nil
+}
+
 func (hs *HTTPStats) SortWithOptions() {
 	hs.Sort(hs.options.Sort, hs.options.Reverse)
 }
 
 type httpStat struct {
-	Uri              string        `yaml:uri`
-	Cnt              int           `yaml:cnt`
-	Status1xx        int           `yaml:status1xx`
-	Status2xx        int           `yaml:status2xx`
-	Status3xx        int           `yaml:status3xx`
-	Status4xx        int           `yaml:status4xx`
-	Status5xx        int           `yaml:status5xx`
-	Method           string        `yaml:method`
-	ResponseTime     *responseTime `yaml:response_time`
-	RequestBodySize  *bodySize     `yaml:request_body_size`
-	ResponseBodySize *bodySize     `yaml:response_body_size`
+	Uri              string            `yaml:"uri"`
+	Cnt              int               `yaml:"cnt"`
+	Status1xx        int               `yaml:"status1xx"`
+	Status2xx        int               `yaml:"status2xx"`
+	Status3xx        int               `yaml:"status3xx"`
+	Status4xx        int               `yaml:"status4xx"`
+	Status5xx        int               `yaml:"status5xx"`
+	Statuses         map[int]int       `yaml:"statuses,omitempty"`
+	StatusInvalid    int               `yaml:"status_invalid,omitempty"`
+	FirstTime        int64             `yaml:"first_time,omitempty"`
+	LastTime         int64             `yaml:"last_time,omitempty"`
+	Apdex            *apdex            `yaml:"apdex,omitempty"`
+	Method           string            `yaml:"method"`
+	OperationID      string            `yaml:"operation_id,omitempty"`
+	Dimensions       map[string]string `yaml:"dimensions,omitempty"`
+	ResponseTime     *responseTime     `yaml:"responsetime"`
+	RequestBodySize  *bodySize         `yaml:"requestbodysize"`
+	ResponseBodySize *bodySize         `yaml:"responsebodysize"`
 }
 
 type httpStats []*httpStat
 
-func newHTTPStat(uri, method string, useResTimePercentile, useRequestBodySizePercentile, useResponseBodySizePercentile bool) *httpStat {
+func newHTTPStat(uri, method string, useResTimePercentile, useRequestBodySizePercentile, useResponseBodySizePercentile bool, sketchAccuracy float64) *httpStat {
 	return &httpStat{
 		Uri:              uri,
 		Method:           method,
-		ResponseTime:     newResponseTime(useResTimePercentile),
-		RequestBodySize:  newBodySize(useRequestBodySizePercentile),
-		ResponseBodySize: newBodySize(useResponseBodySizePercentile),
+		ResponseTime:     newResponseTime(useResTimePercentile, sketchAccuracy),
+		RequestBodySize:  newBodySize(useRequestBodySizePercentile, sketchAccuracy),
+		ResponseBodySize: newBodySize(useResponseBodySizePercentile, sketchAccuracy),
 	}
 }
 
-func (hs *httpStat) Set(status int, restime, reqBodySize, resBodySize float64) {
+func (hs *httpStat) Set(status int, restime, resBodySize, reqBodySize float64) {
 	hs.Cnt++
 	hs.setStatus(status)
+	if hs.Apdex != nil {
+		hs.Apdex.add(restime, status)
+	}
 	hs.ResponseTime.Set(restime)
 	hs.RequestBodySize.Set(reqBodySize)
 	hs.ResponseBodySize.Set(resBodySize)
 }
 
+// setStatus counts status in its class and by its exact code.
+// Statuses outside 100-599, including parsers.InvalidStatus, are counted
+// in StatusInvalid.
 func (hs *httpStat) setStatus(status int) {
+	if status < 100 || status > 599 {
+		hs.StatusInvalid++
+		return
+	}
+
+	if hs.Statuses == nil {
+		hs.Statuses = make(map[int]int)
+	}
+	hs.Statuses[status]++
+
 	if status >= 100 && status <= 199 {
 		hs.Status1xx++
 	} else if status >= 200 && status <= 299 {
@@ -165,6 +439,50 @@ func (hs *httpStat) setStatus(status int) {
 	}
 }
 
+// setTime extends the time range of the requests, in unix nanoseconds, to t.
+func (hs *httpStat) setTime(t time.Time) {
+	nano := t.UnixNano()
+	if hs.FirstTime == 0 || nano < hs.FirstTime {
+		hs.FirstTime = nano
+	}
+	if nano > hs.LastTime {
+		hs.LastTime = nano
+	}
+}
+
+// Throughput returns the requests per second between the first and the
+// last request, or 0 unless they have different times.
+func (hs *httpStat) Throughput() float64 {
+	if hs.LastTime <= hs.FirstTime {
+		return 0
+	}
+
+	return float64(hs.Cnt) / time.Duration(hs.LastTime-hs.FirstTime).Seconds()
+}
+
+// ErrorRate4xx returns the ratio of 4xx responses to all requests.
+func (hs *httpStat) ErrorRate4xx() float64 {
+	if hs.Cnt == 0 {
+		return 0
+	}
+
+	return float64(hs.Status4xx) / float64(hs.Cnt)
+}
+
+// ErrorRate5xx returns the ratio of 5xx responses to all requests.
+func (hs *httpStat) ErrorRate5xx() float64 {
+	if hs.Cnt == 0 {
+		return 0
+	}
+
+	return float64(hs.Status5xx) / float64(hs.Cnt)
+}
+
+// CountStatus returns the number of requests with the exact status code.
+func (hs *httpStat) CountStatus(status int) int {
+	return hs.Statuses[status]
+}
+
 func (hs *httpStat) StrStatus1xx() string {
 	return fmt.Sprint(hs.Status1xx)
 }
@@ -210,23 +528,27 @@ func (hs *httpStat) AvgResponseTime() float64 {
 }
 
 func (hs *httpStat) P1ResponseTime() float64 {
-	return hs.ResponseTime.P1(hs.Cnt)
+	return hs.PercentileResponseTime(1)
 }
 
 func (hs *httpStat) P50ResponseTime() float64 {
-	return hs.ResponseTime.P50(hs.Cnt)
+	return hs.PercentileResponseTime(50)
 }
 
 func (hs *httpStat) P90ResponseTime() float64 {
-	return hs.ResponseTime.P90(hs.Cnt)
+	return hs.PercentileResponseTime(90)
 }
 
 func (hs *httpStat) P99ResponseTime() float64 {
-	return hs.ResponseTime.P99(hs.Cnt)
+	return hs.PercentileResponseTime(99)
+}
+
+func (hs *httpStat) PercentileResponseTime(p float64) float64 {
+	return hs.ResponseTime.Percentile(p)
 }
 
 func (hs *httpStat) StddevResponseTime() float64 {
-	return hs.ResponseTime.Stddev(hs.Cnt)
+	return hs.ResponseTime.Stddev()
 }
 
 // request
@@ -247,84 +569,180 @@ func (hs *httpStat) AvgRequestBodySize() float64 {
 }
 
 func (hs *httpStat) P1RequestBodySize() float64 {
-	return hs.RequestBodySize.P1(hs.Cnt)
+	return hs.PercentileRequestBodySize(1)
 }
 
 func (hs *httpStat) P50RequestBodySize() float64 {
-	return hs.RequestBodySize.P50(hs.Cnt)
+	return hs.PercentileRequestBodySize(50)
 }
 
 func (hs *httpStat) P90RequestBodySize() float64 {
-	return hs.RequestBodySize.P90(hs.Cnt)
+	return hs.PercentileRequestBodySize(90)
 }
 
 func (hs *httpStat) P99RequestBodySize() float64 {
-	return hs.RequestBodySize.P99(hs.Cnt)
+	return hs.PercentileRequestBodySize(99)
+}
+
+func (hs *httpStat) PercentileRequestBodySize(p float64) float64 {
+	return hs.RequestBodySize.Percentile(p)
 }
 
 func (hs *httpStat) StddevRequestBodySize() float64 {
-	return hs.RequestBodySize.Stddev(hs.Cnt)
+	return hs.RequestBodySize.Stddev()
 }
 
 // response
 func (hs *httpStat) MaxResponseBodySize() float64 {
-	return hs.RequestBodySize.Max
+	return hs.ResponseBodySize.Max
 }
 
 func (hs *httpStat) MinResponseBodySize() float64 {
-	return hs.RequestBodySize.Min
+	return hs.ResponseBodySize.Min
 }
 
 func (hs *httpStat) SumResponseBodySize() float64 {
-	return hs.RequestBodySize.Sum
+	return hs.ResponseBodySize.Sum
 }
 
 func (hs *httpStat) AvgResponseBodySize() float64 {
-	return hs.RequestBodySize.Avg(hs.Cnt)
+	return hs.ResponseBodySize.Avg(hs.Cnt)
 }
 
 func (hs *httpStat) P1ResponseBodySize() float64 {
-	return hs.RequestBodySize.P1(hs.Cnt)
+	return hs.PercentileResponseBodySize(1)
 }
 
 func (hs *httpStat) P50ResponseBodySize() float64 {
-	return hs.RequestBodySize.P50(hs.Cnt)
+	return hs.PercentileResponseBodySize(50)
 }
 
 func (hs *httpStat) P90ResponseBodySize() float64 {
-	return hs.RequestBodySize.P90(hs.Cnt)
+	return hs.PercentileResponseBodySize(90)
 }
 
 func (hs *httpStat) P99ResponseBodySize() float64 {
-	return hs.RequestBodySize.P99(hs.Cnt)
+	return hs.PercentileResponseBodySize(99)
+}
+
+func (hs *httpStat) PercentileResponseBodySize(p float64) float64 {
+	return hs.ResponseBodySize.Percentile(p)
 }
 
 func (hs *httpStat) StddevResponseBodySize() float64 {
-	return hs.RequestBodySize.Stddev(hs.Cnt)
+	return hs.ResponseBodySize.Stddev()
+}
+
+var DefaultPercentiles = []float64{1, 50, 99}
+
+// PercentileKey returns the column and sort key of the p-th percentile, e.g. "p99.9".
+func PercentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
 }
 
-func percentRank(l int, n int) int {
-	pLen := (l * n / 100) - 1
+// ParsePercentileKey is the reverse of PercentileKey.
+func ParsePercentileKey(key string) (float64, bool) {
+	if len(key) < 2 || (key[0] != 'p' && key[0] != 'P') {
+		return 0, false
+	}
+
+	p, err := strconv.ParseFloat(key[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, false
+	}
+
+	return p, true
+}
+
+// percentRank returns the 0-based index of the p-th percentile (0 < p <= 100)
+// in l sorted samples, using the nearest-rank method: the smallest sample
+// such that at least p percent of the samples are less than or equal to it.
+// Unlike interpolating methods, the result is always an observed value.
+func percentRank(l int, p float64) int {
+	pLen := int(math.Ceil(float64(l)*p/100)) - 1
 	if pLen < 0 {
 		pLen = 0
 	}
+	if pLen >= l {
+		pLen = l - 1
+	}
 
 	return pLen
 }
 
+// exactPercentile returns the p-th percentile of samples by the nearest-rank
+// method. Unless they are sorted, it sorts a copy, leaving samples as they are.
+func exactPercentile(samples []float64, sorted bool, p float64) float64 {
+	if len(samples) == 0 {
+		return 0.0
+	}
+
+	if !sorted {
+		samples = append([]float64(nil), samples...)
+		sort.Float64s(samples)
+	}
+
+	return samples[percentRank(len(samples), p)]
+}
+
+// sortSamples sorts the samples of every entry, so that their percentiles
+// are read without sorting a copy.
+func (hs *HTTPStats) sortSamples() {
+	for _, s := range hs.stats {
+		s.ResponseTime.sortSamples()
+		s.RequestBodySize.sortSamples()
+		s.ResponseBodySize.sortSamples()
+	}
+}
+
+// welford keeps the running mean and the sum of squared deviations of
+// the samples (Welford's online algorithm), from which the variance is
+// computed in a single pass without keeping the samples.
+type welford struct {
+	N    int     `yaml:"samples"`
+	Mean float64 `yaml:"mean"`
+	M2   float64 `yaml:"m2"`
+}
+
+func (w *welford) add(val float64) {
+	w.N++
+	delta := val - w.Mean
+	w.Mean += delta / float64(w.N)
+	w.M2 += delta * (val - w.Mean)
+}
+
+// stddev returns the population standard deviation.
+func (w *welford) stddev() float64 {
+	if w.N == 0 {
+		return 0.0
+	}
+
+	return math.Sqrt(w.M2 / float64(w.N))
+}
+
 type responseTime struct {
-	Max           float64
-	Min           float64
-	Sum           float64
+	Max           float64 `yaml:"max"`
+	Min           float64 `yaml:"min"`
+	Sum           float64 `yaml:"sum"`
 	usePercentile bool
-	Percentiles   []float64
+	sorted        bool
+	Percentiles   []float64 `yaml:"percentiles,omitempty"`
+	Sketch        *ddSketch `yaml:"sketch,omitempty"`
+	welford       `yaml:",inline"`
 }
 
-func newResponseTime(usePercentile bool) *responseTime {
-	return &responseTime{
+// newResponseTime keeps a sketch instead of every sample when sketchAccuracy > 0.
+func newResponseTime(usePercentile bool, sketchAccuracy float64) *responseTime {
+	res := &responseTime{
 		usePercentile: usePercentile,
 		Percentiles:   make([]float64, 0),
 	}
+
+	if usePercentile && sketchAccuracy > 0 {
+		res.Sketch = newDDSketch(sketchAccuracy, DefaultSketchMaxBins)
+	}
+
+	return res
 }
 
 func (res *responseTime) Set(val float64) {
@@ -337,9 +755,13 @@ func (res *responseTime) Set(val float64) {
 	}
 
 	res.Sum += val
+	res.welford.add(val)
 
-	if res.usePercentile {
+	if res.Sketch != nil {
+		res.Sketch.Add(val)
+	} else if res.usePercentile {
 		res.Percentiles = append(res.Percentiles, val)
+		res.sorted = false
 	}
 }
 
@@ -347,71 +769,49 @@ func (res *responseTime) Avg(cnt int) float64 {
 	return res.Sum / float64(cnt)
 }
 
-func (res *responseTime) P1(cnt int) float64 {
-	if !res.usePercentile {
-		return 0.0
+// Percentile returns the p-th percentile (0 < p <= 100), see percentRank.
+func (res *responseTime) Percentile(p float64) float64 {
+	if res.Sketch != nil {
+		return res.Sketch.Quantile(p / 100)
 	}
 
-	plen := percentRank(cnt, 1)
-	return res.Percentiles[plen]
+	return exactPercentile(res.Percentiles, res.sorted, p)
 }
 
-func (res *responseTime) P50(cnt int) float64 {
-	if !res.usePercentile {
-		return 0.0
+func (res *responseTime) sortSamples() {
+	if !res.sorted {
+		sort.Float64s(res.Percentiles)
+		res.sorted = true
 	}
-
-	plen := percentRank(cnt, 50)
-	return res.Percentiles[plen]
 }
 
-func (res *responseTime) P90(cnt int) float64 {
-	if !res.usePercentile {
-		return 0.0
-	}
-
-	plen := percentRank(cnt, 90)
-	return res.Percentiles[plen]
-}
-
-func (res *responseTime) P99(cnt int) float64 {
-	if !res.usePercentile {
-		return 0.0
-	}
-
-	plen := percentRank(cnt, 99)
-	return res.Percentiles[plen]
-}
-
-func (res *responseTime) Stddev(cnt int) float64 {
-	if !res.usePercentile {
-		return 0.0
-	}
-
-	var stdd float64
-	avg := res.Avg(cnt)
-	n := float64(cnt)
-
-	for _, v := range res.Percentiles {
-		stdd += (v - avg) * (v - avg)
-	}
-
-	return math.Sqrt(stdd / n)
+func (res *responseTime) Stddev() float64 {
+	return res.welford.stddev()
 }
 
 type bodySize struct {
-	Max           float64
-	Min           float64
-	Sum           float64
+	Max           float64 `yaml:"max"`
+	Min           float64 `yaml:"min"`
+	Sum           float64 `yaml:"sum"`
 	usePercentile bool
-	percentiles   []float64
+	sorted        bool
+	Percentiles   []float64 `yaml:"percentiles,omitempty"`
+	Sketch        *ddSketch `yaml:"sketch,omitempty"`
+	welford       `yaml:",inline"`
 }
 
-func newBodySize(usePercentile bool) *bodySize {
-	return &bodySize{
+// newBodySize keeps a sketch instead of every sample when sketchAccuracy > 0.
+func newBodySize(usePercentile bool, sketchAccuracy float64) *bodySize {
+	body := &bodySize{
 		usePercentile: usePercentile,
-		percentiles:   make([]float64, 0),
+		Percentiles:   make([]float64, 0),
 	}
+
+	if usePercentile && sketchAccuracy > 0 {
+		body.Sketch = newDDSketch(sketchAccuracy, DefaultSketchMaxBins)
+	}
+
+	return body
 }
 
 func (body *bodySize) Set(val float64) {
@@ -424,9 +824,13 @@ func (body *bodySize) Set(val float64) {
 	}
 
 	body.Sum += val
+	body.welford.add(val)
 
-	if body.usePercentile {
-		body.percentiles = append(body.percentiles, val)
+	if body.Sketch != nil {
+		body.Sketch.Add(val)
+	} else if body.usePercentile {
+		body.Percentiles = append(body.Percentiles, val)
+		body.sorted = false
 	}
 }
 
@@ -434,54 +838,22 @@ func (body *bodySize) Avg(cnt int) float64 {
 	return body.Sum / float64(cnt)
 }
 
-func (body *bodySize) P1(cnt int) float64 {
-	if !body.usePercentile {
-		return 0.0
+// Percentile returns the p-th percentile (0 < p <= 100), see percentRank.
+func (body *bodySize) Percentile(p float64) float64 {
+	if body.Sketch != nil {
+		return body.Sketch.Quantile(p / 100)
 	}
 
-	plen := percentRank(cnt, 1)
-	return body.percentiles[plen]
+	return exactPercentile(body.Percentiles, body.sorted, p)
 }
 
-func (body *bodySize) P50(cnt int) float64 {
-	if !body.usePercentile {
-		return 0.0
+func (body *bodySize) sortSamples() {
+	if !body.sorted {
+		sort.Float64s(body.Percentiles)
+		body.sorted = true
 	}
-
-	plen := percentRank(cnt, 50)
-	return body.percentiles[plen]
 }
 
-func (body *bodySize) P90(cnt int) float64 {
-	if !body.usePercentile {
-		return 0.0
-	}
-
-	plen := percentRank(cnt, 90)
-	return body.percentiles[plen]
-}
-
-func (body *bodySize) P99(cnt int) float64 {
-	if !body.usePercentile {
-		return 0.0
-	}
-
-	plen := percentRank(cnt, 99)
-	return body.percentiles[plen]
-}
-
-func (body *bodySize) Stddev(cnt int) float64 {
-	if !body.usePercentile {
-		return 0.0
-	}
-
-	var stdd float64
-	avg := body.Avg(cnt)
-	n := float64(cnt)
-
-	for _, v := range body.percentiles {
-		stdd += (v - avg) * (v - avg)
-	}
-
-	return math.Sqrt(stdd / n)
+func (body *bodySize) Stddev() float64 {
+	return body.welford.stddev()
 }
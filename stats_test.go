@@ -0,0 +1,211 @@
+package httpstats
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tkuchiki/gohttpstats/parsers"
+)
+
+func TestPercentRank(t *testing.T) {
+	tests := []struct {
+		l    int
+		p    float64
+		want int
+	}{
+		{l: 1, p: 1, want: 0},
+		{l: 1, p: 99, want: 0},
+		{l: 10, p: 50, want: 4},
+		{l: 10, p: 51, want: 5},
+		{l: 100, p: 1, want: 0},
+		{l: 100, p: 99, want: 98},
+		{l: 100, p: 100, want: 99},
+		{l: 1000, p: 99.9, want: 998},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, percentRank(tt.l, tt.p), "l=%d p=%v", tt.l, tt.p)
+	}
+}
+
+func TestResponseTimePercentiles(t *testing.T) {
+	uniform := make([]float64, 0, 100)
+	for i := 1; i <= 100; i++ {
+		uniform = append(uniform, float64(i))
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(uniform), func(i, j int) {
+		uniform[i], uniform[j] = uniform[j], uniform[i]
+	})
+
+	tests := []struct {
+		name    string
+		samples []float64
+		p1      float64
+		p50     float64
+		p90     float64
+		p99     float64
+		stddev  float64
+	}{
+		{
+			name:    "single",
+			samples: []float64{0.5},
+			p1:      0.5, p50: 0.5, p90: 0.5, p99: 0.5,
+			stddev: 0,
+		},
+		{
+			name:    "shuffled uniform 1..100",
+			samples: uniform,
+			p1:      1, p50: 50, p90: 90, p99: 99,
+			stddev: 28.86607004772212,
+		},
+		{
+			name:    "descending",
+			samples: []float64{9, 7, 5, 5, 4, 4, 4, 2},
+			p1:      2, p50: 4, p90: 9, p99: 9,
+			stddev: 2,
+		},
+		{
+			name:    "outlier",
+			samples: []float64{0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 10},
+			p1:      0.1, p50: 0.1, p90: 0.1, p99: 10,
+			stddev: 2.97,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, sketchAccuracy := range []float64{0, 0.01} {
+			res := newResponseTime(true, sketchAccuracy)
+			for _, v := range tt.samples {
+				res.Set(v)
+			}
+
+			delta := 1e-9
+			if sketchAccuracy > 0 {
+				delta = tt.p99 * sketchAccuracy
+			}
+
+			assert.InDelta(t, tt.p1, res.Percentile(1), delta, "%s p1 sketch=%v", tt.name, sketchAccuracy)
+			assert.InDelta(t, tt.p50, res.Percentile(50), delta, "%s p50 sketch=%v", tt.name, sketchAccuracy)
+			assert.InDelta(t, tt.p90, res.Percentile(90), delta, "%s p90 sketch=%v", tt.name, sketchAccuracy)
+			assert.InDelta(t, tt.p99, res.Percentile(99), delta, "%s p99 sketch=%v", tt.name, sketchAccuracy)
+			assert.InDelta(t, tt.stddev, res.Stddev(), 1e-9, "%s stddev sketch=%v", tt.name, sketchAccuracy)
+		}
+	}
+}
+
+func TestPercentileDoesNotSortSamples(t *testing.T) {
+	stats := NewHTTPStats(true, false, false, NewPrintOptions())
+	for _, v := range []float64{0.3, 0.1, 0.2} {
+		stats.Set("/foo", "GET", 200, v, 0, 0)
+	}
+
+	res := stats.Stats()[0].ResponseTime
+	assert.Equal(t, 0.3, res.Percentile(100))
+	assert.Equal(t, []float64{0.3, 0.1, 0.2}, res.Percentiles)
+
+	stats.Sort(SortCount, false)
+	assert.Equal(t, []float64{0.1, 0.2, 0.3}, res.Percentiles)
+	assert.Equal(t, 0.1, res.Percentile(1))
+}
+
+func TestStddevWithoutPercentiles(t *testing.T) {
+	body := newBodySize(false, 0)
+	for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		body.Set(v)
+	}
+
+	assert.Empty(t, body.Percentiles)
+	assert.Equal(t, 0.0, body.Percentile(99))
+	assert.InDelta(t, 2.0, body.Stddev(), 1e-9)
+}
+
+func TestRequestAndResponseBodySizeAreSeparate(t *testing.T) {
+	stats := NewHTTPStats(false, true, true, NewPrintOptions())
+	stats.Set("/upload", "POST", 201, 0.1, 10, 1000)
+	stats.Set("/upload", "POST", 201, 0.1, 30, 3000)
+
+	s := stats.Stats()[0]
+	assert.Equal(t, float64(10), s.MinResponseBodySize())
+	assert.Equal(t, float64(30), s.MaxResponseBodySize())
+	assert.Equal(t, float64(40), s.SumResponseBodySize())
+	assert.Equal(t, float64(20), s.AvgResponseBodySize())
+	assert.Equal(t, float64(30), s.P99ResponseBodySize())
+	assert.Equal(t, float64(10), s.StddevResponseBodySize())
+
+	assert.Equal(t, float64(1000), s.MinRequestBodySize())
+	assert.Equal(t, float64(3000), s.MaxRequestBodySize())
+	assert.Equal(t, float64(4000), s.SumRequestBodySize())
+	assert.Equal(t, float64(2000), s.AvgRequestBodySize())
+	assert.Equal(t, float64(3000), s.P99RequestBodySize())
+	assert.Equal(t, float64(1000), s.StddevRequestBodySize())
+}
+
+func TestLimit(t *testing.T) {
+	stats := NewHTTPStats(false, false, false, NewPrintOptions())
+	stats.SetLimit(2, "OTHER")
+
+	stats.Set("/a", "GET", 200, 0.1, 0, 0)
+	stats.Set("/b", "GET", 200, 0.1, 0, 0)
+	stats.Set("/c", "GET", 200, 0.1, 0, 0)
+	stats.Set("/a", "GET", 200, 0.1, 0, 0)
+	stats.Set("/d", "POST", 200, 0.1, 0, 0)
+
+	s := stats.Stats()
+	assert.Equal(t, 3, len(s))
+	assert.Equal(t, "/a", s[0].Uri)
+	assert.Equal(t, 2, s[0].Cnt)
+	assert.Equal(t, "OTHER", s[2].Uri)
+	assert.Equal(t, 2, s[2].Cnt)
+	assert.Equal(t, 2, stats.OverflowCount())
+}
+
+func TestExactStatuses(t *testing.T) {
+	hs := NewHTTPStats(false, false, false, NewPrintOptions())
+	for _, status := range []int{404, 499, 499, 502, 504, 0, 600} {
+		hs.Set("/foo", "GET", status, 0.1, 0, 0)
+	}
+
+	s := hs.Stats()[0]
+	assert.Equal(t, 7, s.Count())
+	assert.Equal(t, 3, s.Status4xx)
+	assert.Equal(t, 2, s.Status5xx)
+	assert.Equal(t, map[int]int{404: 1, 499: 2, 502: 1, 504: 1}, s.Statuses)
+	assert.Equal(t, 2, s.StatusInvalid)
+
+	c, ok := lookupColumn("status_499")
+	assert.True(t, ok)
+	assert.Equal(t, "2", c.format(s))
+
+	_, ok = lookupColumn("status_600")
+	assert.False(t, ok)
+}
+
+func TestThroughputAndErrorRates(t *testing.T) {
+	hs := NewHTTPStats(false, false, false, NewPrintOptions())
+	for i, status := range []int{200, 200, 404, 500, 200} {
+		hs.SetHTTPStat(&parsers.HTTPStat{
+			Uri:    "/foo",
+			Method: "GET",
+			Time:   fmt.Sprintf("2018-10-14T05:58:%02d+09:00", i*2),
+			Status: status,
+		})
+	}
+	hs.Set("/bar", "GET", 200, 0.1, 0, 0)
+	assert.Equal(t, InvalidTimeErr, hs.SetHTTPStat(&parsers.HTTPStat{Uri: "/bar", Method: "GET", Time: "broken", Status: 200}))
+
+	foo := hs.Stats()[0]
+	assert.Equal(t, 5.0/8, foo.Throughput())
+	assert.Equal(t, 0.2, foo.ErrorRate4xx())
+	assert.Equal(t, 0.2, foo.ErrorRate5xx())
+
+	bar := hs.Stats()[1]
+	assert.Equal(t, float64(0), bar.Throughput())
+
+	c, _ := lookupColumn("5xx_rate")
+	assert.Equal(t, "20.00", c.format(foo))
+
+	hs.Sort("rps", true)
+	assert.Equal(t, "/foo", hs.Stats()[0].Uri)
+}
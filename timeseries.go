@@ -0,0 +1,187 @@
+package httpstats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tkuchiki/gohttpstats/parsers"
+	"github.com/tkuchiki/parsetime"
+)
+
+// TimeSeries keeps a separate HTTPStats per fixed time window, such as
+// every minute, so that it shows when the stats of an entry changed.
+// Each window is configured like the HTTPStats it was created from.
+type TimeSeries struct {
+	template  *HTTPStats
+	interval  time.Duration
+	parseTime parsetime.ParseTime
+	location  *time.Location
+	windows   map[int64]*HTTPStats
+}
+
+// TimeWindow is the stats of the requests from Start until Start + interval.
+type TimeWindow struct {
+	Start time.Time
+	Stats *HTTPStats
+}
+
+// NewTimeSeries returns a TimeSeries of windows of interval, whose
+// times are parsed like the filter times, in location ("" for local time).
+// Windows are aligned to multiples of interval since the zero time, in UTC.
+func NewTimeSeries(hs *HTTPStats, interval time.Duration, location string) (*TimeSeries, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive: %s", interval)
+	}
+
+	p, err := parsetime.NewParseTime(location)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := time.Local
+	if location != "" {
+		loc, err = time.LoadLocation(location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &TimeSeries{
+		template:  hs,
+		interval:  interval,
+		parseTime: p,
+		location:  loc,
+		windows:   make(map[int64]*HTTPStats),
+	}, nil
+}
+
+// Set counts the request in the window that contains t.
+func (ts *TimeSeries) Set(t time.Time, uri, method string, status int, restime, resBodySize, reqBodySize float64, fields map[string]string) {
+	start := t.Truncate(ts.interval).UnixNano()
+
+	hs, ok := ts.windows[start]
+	if !ok {
+		hs = ts.template.emptyCopy()
+		ts.windows[start] = hs
+	}
+
+	hs.set(uri, method, status, restime, resBodySize, reqBodySize, fields).setTime(t)
+}
+
+// Aggregate is HTTPStats.Aggregate for a TimeSeries, with the filter of
+// the HTTPStats it was created from, which sees the times parsed in the
+// location of ts. Lines whose time cannot be parsed are counted as failed.
+func (ts *TimeSeries) Aggregate(ctx context.Context, parser parsers.Parser) (*AggregateResult, error) {
+	result, err := aggregate(ctx, parser, ts.parseTime, ts.template.doFilterTime, func(stat *parsers.HTTPStat, t time.Time, timeErr error) error {
+		if timeErr != nil {
+			return timeErr
+		}
+		if t.IsZero() {
+			return SkipReadLineErr
+		}
+
+		ts.Set(t, stat.Uri, stat.Method, stat.Status, stat.ResponseTime, stat.BodySize, stat.RequestBodySize, stat.Fields)
+		return nil
+	})
+	for _, hs := range ts.windows {
+		hs.sortSamples()
+	}
+
+	return result, err
+}
+
+// Windows returns the windows that have requests, oldest first.
+func (ts *TimeSeries) Windows() []*TimeWindow {
+	starts := make([]int64, 0, len(ts.windows))
+	for start := range ts.windows {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i] < starts[j]
+	})
+
+	windows := make([]*TimeWindow, 0, len(starts))
+	for _, start := range starts {
+		windows = append(windows, &TimeWindow{
+			Start: time.Unix(0, start).In(ts.location),
+			Stats: ts.windows[start],
+		})
+	}
+
+	return windows
+}
+
+// timeSeriesEntry is one window of the series of an entry.
+type timeSeriesEntry struct {
+	start time.Time
+	stats *HTTPStats
+	stat  *httpStat
+}
+
+// series returns the windows of every entry, grouped by entry in order of
+// first appearance, and oldest first within an entry.
+func (ts *TimeSeries) series() []*timeSeriesEntry {
+	aggregates := ts.template.Aggregates()
+	order := make([]string, 0)
+	byKey := make(map[string][]*timeSeriesEntry)
+
+	for _, w := range ts.Windows() {
+		for _, s := range w.Stats.stats {
+			key := s.key(aggregates)
+			if _, ok := byKey[key]; !ok {
+				order = append(order, key)
+			}
+			byKey[key] = append(byKey[key], &timeSeriesEntry{start: w.Start, stats: w.Stats, stat: s})
+		}
+	}
+
+	series := make([]*timeSeriesEntry, 0)
+	for _, key := range order {
+		series = append(series, byKey[key]...)
+	}
+
+	return series
+}
+
+// Print prints the time series of every entry with the print options of
+// the HTTPStats it was created from, with the window start as first column.
+func (ts *TimeSeries) Print() error {
+	return ts.template.printOptions.print(ts)
+}
+
+func (ts *TimeSeries) headers() []string {
+	return append([]string{"Time"}, ts.template.headers()...)
+}
+
+func (ts *TimeSeries) rows() [][]string {
+	// the windows share the columns of the template
+	cols := ts.template.columns()
+	series := ts.series()
+	rows := make([][]string, 0, len(series))
+	for _, e := range series {
+		rows = append(rows, append([]string{e.start.Format(time.RFC3339)}, formatRow(cols, e.stat)...))
+	}
+
+	return rows
+}
+
+// jsonTimeStat is a jsonStat with the start of its window.
+type jsonTimeStat struct {
+	Time string `json:"time"`
+	*jsonStat
+}
+
+func (ts *TimeSeries) values() []interface{} {
+	series := ts.series()
+	values := make([]interface{}, 0, len(series))
+	for _, e := range series {
+		values = append(values, &jsonTimeStat{
+			Time:     e.start.Format(time.RFC3339),
+			jsonStat: e.stats.jsonStat(e.stat),
+		})
+	}
+
+	return values
+}
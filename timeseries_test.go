@@ -0,0 +1,49 @@
+package httpstats
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tkuchiki/gohttpstats/parsers"
+)
+
+func TestTimeSeries(t *testing.T) {
+	data := bytes.NewBufferString(`{"time":"2018-10-14T05:58:05Z","method":"GET","uri":"/foo","status":200,"size":10,"apptime":0.1}
+{"time":"2018-10-14T05:58:59Z","method":"GET","uri":"/bar","status":200,"size":10,"apptime":0.2}
+{"time":"2018-10-14T05:59:00Z","method":"GET","uri":"/foo","status":200,"size":10,"apptime":0.3}
+{"time":"2018-10-14T05:59:30Z","method":"GET","uri":"/foo","status":500,"size":10,"apptime":0.5}
+{"time":"broken","method":"GET","uri":"/foo","status":200,"size":10,"apptime":0.1}
+`)
+
+	keys := parsers.NewJSONKeys("uri", "apptime", "reqtime", "size", "status", "method", "time")
+	parser := parsers.NewJSONParser(data, keys, false)
+
+	po := NewPrintOptions()
+	po.SetFormat("tsv")
+	var buf bytes.Buffer
+	po.SetWriter(&buf)
+
+	hs := NewHTTPStats(true, false, false, po)
+	assert.Nil(t, hs.SetColumns([]string{"count", "uri", "max"}))
+
+	ts, err := NewTimeSeries(hs, time.Minute, "UTC")
+	assert.Nil(t, err)
+
+	result, err := ts.Aggregate(context.Background(), parser)
+	assert.Nil(t, err)
+	assert.Equal(t, &AggregateResult{Read: 5, Failed: 1}, result)
+
+	windows := ts.Windows()
+	assert.Len(t, windows, 2)
+	assert.Equal(t, "2018-10-14T05:58:00Z", windows[0].Start.Format(time.RFC3339))
+	assert.Equal(t, 2, windows[1].Stats.Stats()[0].Count())
+
+	ts.Print()
+	assert.Equal(t, "Time\tCount\tUri\tMax\n"+
+		"2018-10-14T05:58:00Z\t1\t/foo\t0.100\n"+
+		"2018-10-14T05:59:00Z\t2\t/foo\t0.500\n"+
+		"2018-10-14T05:58:00Z\t1\t/bar\t0.200\n", buf.String())
+}
@@ -0,0 +1,153 @@
+package httpstats
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// URIGroup counts the uris matching a pattern as one entry shown as its template.
+// A group loaded from an OpenAPI document also matches on the method and
+// carries the operationId.
+type URIGroup struct {
+	re          *regexp.Regexp
+	template    string
+	method      string
+	operationID string
+	// overrides HTTPStats.SetApdexThreshold when positive
+	apdexThreshold float64
+}
+
+// NewURIGroup returns a group for the regexp pattern displayed as template,
+// e.g. NewURIGroup(`^/users/[0-9]+/posts$`, "/users/{id}/posts").
+// An empty template displays the pattern itself.
+func NewURIGroup(pattern, template string) (*URIGroup, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if template == "" {
+		template = pattern
+	}
+
+	return &URIGroup{
+		re:       re,
+		template: template,
+	}, nil
+}
+
+// CompileURIGroup compiles either a route pattern or a regexp.
+//
+// A route pattern such as "/users/:id/posts" or "/users/{id}/posts" has
+// placeholder segments matching one path segment each ("*" matches the rest
+// of the path) and is displayed as written.
+//
+// A regexp whose named groups are its only metacharacters, such as
+// `^/users/(?P<id>[0-9]+)/posts$`, is displayed as "/users/{id}/posts".
+// Any other regexp is displayed as its source.
+func CompileURIGroup(pattern string) (*URIGroup, error) {
+	if isRoutePattern(pattern) {
+		return NewURIGroup(routeToRegexp(pattern), pattern)
+	}
+
+	return NewURIGroup(pattern, regexpTemplate(pattern))
+}
+
+func compileURIGroups(patterns []string) ([]*URIGroup, error) {
+	groups := make([]*URIGroup, 0, len(patterns))
+	for _, pattern := range patterns {
+		g, err := CompileURIGroup(pattern)
+		if err != nil {
+			return []*URIGroup{}, err
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, nil
+}
+
+func (g *URIGroup) Match(uri string) bool {
+	return g.re.MatchString(uri)
+}
+
+func (g *URIGroup) matchRequest(method, uri string) bool {
+	if g.method != "" && !strings.EqualFold(g.method, method) {
+		return false
+	}
+
+	return g.Match(uri)
+}
+
+func (g *URIGroup) Method() string {
+	return g.method
+}
+
+func (g *URIGroup) OperationID() string {
+	return g.operationID
+}
+
+func (g *URIGroup) Template() string {
+	return g.template
+}
+
+func (g *URIGroup) String() string {
+	return g.re.String()
+}
+
+var (
+	routePlaceholderRe = regexp.MustCompile(`^(?::[A-Za-z_][A-Za-z0-9_]*|\{[A-Za-z_][A-Za-z0-9_]*\}|\*)$`)
+	routeLiteralRe     = regexp.MustCompile(`^[A-Za-z0-9._~!$&'@,;=%-]*$`)
+	namedGroupRe       = regexp.MustCompile(`\(\?P<([A-Za-z_][A-Za-z0-9_]*)>[^()]*\)`)
+)
+
+func isRoutePattern(pattern string) bool {
+	if !strings.HasPrefix(pattern, "/") {
+		return false
+	}
+
+	placeholders := 0
+	for _, segment := range strings.Split(pattern[1:], "/") {
+		if routePlaceholderRe.MatchString(segment) {
+			placeholders++
+		} else if !routeLiteralRe.MatchString(segment) {
+			return false
+		}
+	}
+
+	return placeholders > 0
+}
+
+func routeToRegexp(route string) string {
+	segments := strings.Split(route[1:], "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "*":
+			segments[i] = ".*"
+		case strings.HasPrefix(segment, ":"):
+			segments[i] = fmt.Sprintf("(?P<%s>[^/]+)", segment[1:])
+		case strings.HasPrefix(segment, "{"):
+			segments[i] = fmt.Sprintf("(?P<%s>[^/]+)", segment[1:len(segment)-1])
+		default:
+			segments[i] = regexp.QuoteMeta(segment)
+		}
+	}
+
+	return "^/" + strings.Join(segments, "/") + "$"
+}
+
+// regexpTemplate derives the display template of a regexp from its named
+// groups, or returns the regexp itself when it has other metacharacters.
+func regexpTemplate(pattern string) string {
+	if !namedGroupRe.MatchString(pattern) {
+		return pattern
+	}
+
+	anchored := strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+	literal := namedGroupRe.ReplaceAllString(anchored, "")
+	if regexp.QuoteMeta(literal) != literal {
+		return pattern
+	}
+
+	return namedGroupRe.ReplaceAllString(anchored, "{$1}")
+}
@@ -0,0 +1,89 @@
+package httpstats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileURIGroup(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		template string
+		match    []string
+		nomatch  []string
+	}{
+		{
+			pattern:  "/users/:id/posts",
+			template: "/users/:id/posts",
+			match:    []string{"/users/1/posts", "/users/jane/posts"},
+			nomatch:  []string{"/users/1/posts/2", "/users//posts", "/users/1/2/posts"},
+		},
+		{
+			pattern:  "/users/{id}/posts/{post_id}",
+			template: "/users/{id}/posts/{post_id}",
+			match:    []string{"/users/1/posts/2"},
+			nomatch:  []string{"/users/1/posts"},
+		},
+		{
+			pattern:  "/static/*",
+			template: "/static/*",
+			match:    []string{"/static/css/app.css"},
+			nomatch:  []string{"/staticfoo"},
+		},
+		{
+			pattern:  `^/users/(?P<id>[0-9]+)/posts$`,
+			template: "/users/{id}/posts",
+			match:    []string{"/users/1/posts"},
+			nomatch:  []string{"/users/jane/posts"},
+		},
+		{
+			pattern:  `^/users/[0-9]+/posts$`,
+			template: `^/users/[0-9]+/posts$`,
+			match:    []string{"/users/1/posts"},
+		},
+		{
+			pattern:  `/diary/entry/\d+`,
+			template: `/diary/entry/\d+`,
+			match:    []string{"/diary/entry/1", "/api/diary/entry/1/comments"},
+		},
+	}
+
+	for _, tt := range tests {
+		g, err := CompileURIGroup(tt.pattern)
+		assert.Nil(t, err, tt.pattern)
+		assert.Equal(t, tt.template, g.Template(), tt.pattern)
+
+		for _, uri := range tt.match {
+			assert.True(t, g.Match(uri), "%s should match %s", tt.pattern, uri)
+		}
+		for _, uri := range tt.nomatch {
+			assert.False(t, g.Match(uri), "%s should not match %s", tt.pattern, uri)
+		}
+	}
+
+	_, err := CompileURIGroup(`^/users/(`)
+	assert.NotNil(t, err)
+}
+
+func TestURIGroupsFirstMatchWins(t *testing.T) {
+	stats := NewHTTPStats(false, false, false, NewPrintOptions())
+	err := stats.SetURICapturingGroups([]string{"/users/me", "/users/:id", "/users/*"})
+	assert.Nil(t, err)
+
+	g, err := NewURIGroup(`^/posts/[0-9]+$`, "/posts/{id}")
+	assert.Nil(t, err)
+
+	stats.Set("/users/me", "GET", 200, 0.1, 0, 0)
+	stats.Set("/users/42", "GET", 200, 0.1, 0, 0)
+	stats.Set("/users/42/posts", "GET", 200, 0.1, 0, 0)
+
+	s := stats.Stats()
+	assert.Equal(t, "/users/me", s[0].Uri)
+	assert.Equal(t, "/users/:id", s[1].Uri)
+	assert.Equal(t, "/users/*", s[2].Uri)
+
+	stats.SetURIGroups([]*URIGroup{g})
+	stats.Set("/posts/1", "GET", 200, 0.1, 0, 0)
+	assert.Equal(t, "/posts/{id}", stats.Stats()[3].Uri)
+}